@@ -0,0 +1,171 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	assetTypesPath  = "api.json"
+	assetSearchPath = "api/search"
+)
+
+// DefaultAssetSearchPageSize is how many assets Search asks for at once when
+// the search doesn't say. It is the most CloudHealth returns per page.
+const DefaultAssetSearchPageSize = 100
+
+// AssetService wraps the asset metadata endpoints, /api.json and
+// /api/<Asset>.json, which describe the assets perspective rules refer to.
+type AssetService struct {
+	client *Client
+}
+
+// AssetAttribute is one field of an asset type.
+type AssetAttribute struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Nullable bool   `json:"nullable"`
+}
+
+// AssetRelation links an asset type to another, e.g. an AwsInstance to its
+// AwsAccount.
+type AssetRelation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// AssetType describes the fields of an asset type.
+type AssetType struct {
+	Name       string           `json:"name"`
+	Attributes []AssetAttribute `json:"attributes"`
+	Relations  []AssetRelation  `json:"relations"`
+}
+
+// AssetSearch is a query of the asset search endpoint.
+type AssetSearch struct {
+	// Asset type to search, e.g. AwsInstance
+	Name string
+	// CloudHealth search query, e.g. is_active=1. Empty matches every asset.
+	Query string
+	// Attributes to return. Empty returns them all.
+	Fields []string
+	// Assets per page. 0 means DefaultAssetSearchPageSize.
+	PageSize int
+	// Stop after this many assets. 0 means no limit.
+	Limit int
+}
+
+func assetTypePath(name string) string {
+	return fmt.Sprintf("api/%s.json", url.PathEscape(name))
+}
+
+// Types lists the names of every asset type, sorted.
+func (s *AssetService) Types(ctx context.Context) ([]string, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, assetTypesPath, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The names come either as a list or wrapped in {"list": [...]}
+	var types []string
+	if err := json.Unmarshal(body, &types); err != nil {
+		var wrapped struct {
+			List []string `json:"list"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("Unable to parse asset types because %s", err)
+		}
+		types = wrapped.List
+	}
+	if types == nil {
+		types = make([]string, 0)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// Describe loads the attributes and relations of an asset type.
+func (s *AssetService) Describe(ctx context.Context, name string) (*AssetType, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, assetTypePath(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	assetType := new(AssetType)
+	if err := json.Unmarshal(body, assetType); err != nil {
+		return nil, fmt.Errorf("Unable to parse asset type %s because %s", name, err)
+	}
+	if assetType.Name == "" {
+		assetType.Name = name
+	}
+	return assetType, nil
+}
+
+// Search returns the assets matching the search, fetching a page at a time.
+// Numbers are kept as json.Number.
+func (s *AssetService) Search(ctx context.Context, search AssetSearch) ([]map[string]interface{}, error) {
+	pageSize := search.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultAssetSearchPageSize
+	}
+
+	result := make([]map[string]interface{}, 0)
+	var previous []byte
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("api_version", "2")
+		query.Set("name", search.Name)
+		if search.Query != "" {
+			query.Set("query", search.Query)
+		}
+		if len(search.Fields) > 0 {
+			query.Set("fields", strings.Join(search.Fields, ","))
+		}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(pageSize))
+		req, err := s.client.newRequest(ctx, http.MethodGet, assetSearchPath, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := s.client.do(req)
+		if err != nil {
+			return nil, err
+		}
+		// The endpoint ignored the page and sent the last one again
+		if previous != nil && bytes.Equal(body, previous) {
+			break
+		}
+		previous = body
+
+		var assets []map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&assets); err != nil {
+			return nil, fmt.Errorf("Unable to parse search results for %s because %s", search.Name, err)
+		}
+		result = append(result, assets...)
+		if search.Limit > 0 && len(result) >= search.Limit {
+			return result[:search.Limit], nil
+		}
+		// A short page is the last one
+		if len(assets) < pageSize {
+			break
+		}
+	}
+	return result, nil
+}
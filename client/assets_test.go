@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetTypes(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api.json", r.URL.Path)
+		w.Write([]byte(`{"list": ["AwsInstance", "AwsAccount"]}`))
+	})
+
+	types, err := c.Assets.Types(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AwsAccount", "AwsInstance"}, types)
+}
+
+func TestAssetTypesList(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["AwsAccount"]`))
+	})
+
+	types, err := c.Assets.Types(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AwsAccount"}, types)
+}
+
+func TestDescribeAsset(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/AwsAccount.json", r.URL.Path)
+		w.Write([]byte(`{
+			"name": "AwsAccount",
+			"attributes": [
+				{"name": "id", "kind": "integer", "nullable": false},
+				{"name": "owner_id", "kind": "string", "nullable": true}
+			],
+			"relations": [{"name": "billing_account", "kind": "AwsAccount"}]
+		}`))
+	})
+
+	assetType, err := c.Assets.Describe(context.Background(), "AwsAccount")
+	assert.Nil(t, err)
+	assert.Equal(t, "AwsAccount", assetType.Name)
+	assert.Equal(t, []AssetAttribute{
+		{Name: "id", Kind: "integer"},
+		{Name: "owner_id", Kind: "string", Nullable: true},
+	}, assetType.Attributes)
+	assert.Equal(t, "billing_account", assetType.Relations[0].Name)
+}
+
+func TestDescribeUnknownAsset(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Record not found"}`))
+	})
+
+	_, err := c.Assets.Describe(context.Background(), "Missing")
+	apiErr, ok := err.(*APIError)
+	assert.True(t, ok, "error is an *APIError")
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestSearchAssetsPages(t *testing.T) {
+	var pages []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/search", r.URL.Path)
+		query := r.URL.Query()
+		assert.Equal(t, "AwsInstance", query.Get("name"))
+		assert.Equal(t, "is_active=1", query.Get("query"))
+		assert.Equal(t, "name,instance_type", query.Get("fields"))
+		assert.Equal(t, "2", query.Get("per_page"))
+		pages = append(pages, query.Get("page"))
+		switch query.Get("page") {
+		case "1":
+			w.Write([]byte(`[{"name": "a", "instance_type": "t3.small"}, {"name": "b", "instance_type": "t3.large"}]`))
+		default:
+			w.Write([]byte(`[{"name": "c", "instance_type": null}]`))
+		}
+	})
+
+	assets, err := c.Assets.Search(context.Background(), AssetSearch{
+		Name:     "AwsInstance",
+		Query:    "is_active=1",
+		Fields:   []string{"name", "instance_type"},
+		PageSize: 2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2"}, pages)
+	assert.Equal(t, 3, len(assets))
+	assert.Equal(t, "c", assets[2]["name"])
+	assert.Nil(t, assets[2]["instance_type"])
+}
+
+func TestSearchAssetsPageIgnored(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[{"id": 1}, {"id": 2}]`))
+	})
+
+	assets, err := c.Assets.Search(context.Background(), AssetSearch{Name: "AwsAccount", PageSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 2, len(assets))
+	assert.Equal(t, json.Number("1"), assets[0]["id"])
+}
+
+func TestSearchAssetsLimit(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[{"id": 1}, {"id": 2}]`))
+	})
+
+	assets, err := c.Assets.Search(context.Background(), AssetSearch{Name: "AwsAccount", PageSize: 2, Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 1, len(assets))
+}
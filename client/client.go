@@ -0,0 +1,302 @@
+// Package client is a small typed client for the CloudHealth REST API.
+//
+// It is used by the Terraform provider but has no dependency on Terraform, so
+// it can be reused by other Go tooling that needs to talk to CloudHealth.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the base URL of the public CloudHealth API.
+const DefaultEndpoint = "https://chapi.cloudhealthtech.com"
+
+// Config holds everything needed to build a Client.
+type Config struct {
+	// Endpoint is the base URL of the API. Defaults to DefaultEndpoint.
+	Endpoint string
+	// APIKey is the CloudHealth API key used to authenticate every request.
+	APIKey string
+	// HTTPClient is used to send requests. If unset, one is built from the
+	// Timeout, ProxyURL, CABundleFile and InsecureSkipVerify settings below.
+	HTTPClient *http.Client
+
+	// Timeout limits how long a single request may take. Zero means no limit.
+	Timeout time.Duration
+	// ProxyURL sends every request through this proxy. If empty, the usual
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables are used.
+	ProxyURL string
+	// CABundleFile is a PEM file of CA certificates to trust in addition to
+	// the system pool.
+	CABundleFile string
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool
+
+	// ClientAPIID, if set, makes every request act on that customer tenant.
+	// This is for CloudHealth partners managing their customers' accounts.
+	ClientAPIID string
+
+	// MaxRetries is how many times a failed request is retried. Zero disables
+	// retries.
+	MaxRetries int
+	// RetryMaxWait caps the wait between two attempts, including any wait
+	// asked for by a Retry-After header.
+	RetryMaxWait time.Duration
+
+	// Limiter throttles every request sent, including retries. It may be
+	// shared between clients. If unset, requests are not throttled.
+	Limiter *Limiter
+
+	// DumpDir, if set, is a directory where every request and response is
+	// written as a JSON file, for debugging. The API key is redacted.
+	DumpDir string
+
+	// Redactor scrubs the API key from errors and logs. If unset, the client
+	// creates its own. The API key is always added to it.
+	Redactor *Redactor
+
+	// KeepUnknownFields decodes responses with DecodePerspectiveTolerant, so
+	// that fields added to the API don't fail decoding. By default they do.
+	KeepUnknownFields bool
+}
+
+// Client talks to the CloudHealth API. Each group of endpoints is exposed as
+// a service, e.g. client.Perspectives.Get(ctx, id).
+type Client struct {
+	Perspectives *PerspectiveService
+	Assets       *AssetService
+	Reports      *ReportService
+
+	baseURL     *url.URL
+	apiKey      string
+	clientAPIID string
+	httpClient  *http.Client
+	redactor    *Redactor
+	limiter     *Limiter
+
+	maxRetries   int
+	retryMaxWait time.Duration
+
+	dumpDir string
+
+	keepUnknownFields bool
+}
+
+// New builds a Client from config.
+func New(config Config) (*Client, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("An API key is required")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid endpoint %s because %s", endpoint, err)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient, err = newHTTPClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.DumpDir != "" {
+		if err := os.MkdirAll(config.DumpDir, 0700); err != nil {
+			return nil, fmt.Errorf("Failed to create debug dump directory %s because %s", config.DumpDir, err)
+		}
+	}
+
+	redactor := config.Redactor
+	if redactor == nil {
+		redactor = new(Redactor)
+	}
+	redactor.Add(config.APIKey)
+
+	c := &Client{
+		baseURL:     baseURL,
+		apiKey:      config.APIKey,
+		clientAPIID: config.ClientAPIID,
+		httpClient:  httpClient,
+		redactor:    redactor,
+		limiter:     config.Limiter,
+
+		maxRetries:   config.MaxRetries,
+		retryMaxWait: config.RetryMaxWait,
+
+		dumpDir: config.DumpDir,
+
+		keepUnknownFields: config.KeepUnknownFields,
+	}
+	c.Perspectives = &PerspectiveService{client: c}
+	c.Assets = &AssetService{client: c}
+	c.Reports = &ReportService{client: c}
+	return c, nil
+}
+
+// ClientAPIID returns the customer tenant the client acts on, or "" for the
+// account of the API key.
+func (c *Client) ClientAPIID() string {
+	return c.clientAPIID
+}
+
+// WithClientAPIID returns a copy of the client that acts on the given customer
+// tenant. The copy shares its connections and limiter with the original. An
+// empty id returns the client unchanged.
+func (c *Client) WithClientAPIID(id string) *Client {
+	if id == "" || id == c.clientAPIID {
+		return c
+	}
+	tenant := *c
+	tenant.clientAPIID = id
+	tenant.Perspectives = &PerspectiveService{client: &tenant}
+	tenant.Assets = &AssetService{client: &tenant}
+	tenant.Reports = &ReportService{client: &tenant}
+	return &tenant
+}
+
+// newHTTPClient builds an http.Client with the transport settings in config
+func newHTTPClient(config Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy URL %s because %s", config.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CABundleFile != "" {
+		pem, err := ioutil.ReadFile(config.CABundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA bundle %s because %s", config.CABundleFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle %s", config.CABundleFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}, nil
+}
+
+// newRequest builds a request for path, which is relative to the endpoint.
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body []byte) (*http.Request, error) {
+	u, err := c.baseURL.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if c.clientAPIID != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("client_api_id", c.clientAPIID)
+	}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	return req, nil
+}
+
+// do sends req and returns the response body, retrying where it is safe to.
+// Any non-2xx status is returned as an *APIError. The API key is scrubbed from
+// any error returned.
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	body, err := c.send(req)
+	for attempt := 0; err != nil && attempt < c.maxRetries && shouldRetry(req, err); attempt++ {
+		wait := retryWait(attempt, err, c.retryMaxWait)
+		log.Printf("[WARN] CloudHealth %s %s failed, retrying in %s (retry %d of %d): %s",
+			req.Method, req.URL.Path, wait, attempt+1, c.maxRetries, c.redactor.Redact(err.Error()))
+		if sleepErr := sleepContext(req.Context(), wait); sleepErr != nil {
+			break
+		}
+
+		retryReq, rewindErr := rewind(req)
+		if rewindErr != nil {
+			break
+		}
+		body, err = c.send(retryReq)
+	}
+	return body, c.redactor.RedactError(err)
+}
+
+func (c *Client) send(req *http.Request) ([]byte, error) {
+	release, err := c.limiter.Acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	resp, body, err := c.roundTrip(req)
+	if err != nil {
+		c.dump(req, start, 0, nil, err)
+		return nil, err
+	}
+	c.dump(req, start, resp.StatusCode, body, nil)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Printf("[DEBUG] Response to CloudHealth %s %s is: %s", req.Method, req.URL.Path, c.redactor.Redact(string(body)))
+		apiErr := newAPIError(req, resp.StatusCode, body)
+		apiErr.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, apiErr
+	}
+	return body, nil
+}
+
+// roundTrip sends req and reads the whole response body
+func (c *Client) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read response to %s %s because %s", req.Method, req.URL.Path, err)
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequiresAPIKey(t *testing.T) {
+	_, err := New(Config{})
+	assert.NotNil(t, err)
+}
+
+func TestCABundleFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// Without the bundle the self-signed certificate is rejected
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key"})
+	assert.Nil(t, err)
+	_, err = c.Perspectives.List(context.Background())
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "cht-ca")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(t, ioutil.WriteFile(bundle, certPEM, 0600))
+
+	c, err = New(Config{Endpoint: server.URL, APIKey: "key", CABundleFile: bundle})
+	assert.Nil(t, err)
+	_, err = c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key", InsecureSkipVerify: true})
+	assert.Nil(t, err)
+	_, err = c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+}
+
+func TestInvalidCABundleFile(t *testing.T) {
+	_, err := New(Config{APIKey: "key", CABundleFile: "/does/not/exist.pem"})
+	assert.NotNil(t, err)
+}
+
+func TestProxyURL(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the target
+		proxied = r.URL.String()
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	c, err := New(Config{Endpoint: "http://cloudhealth.invalid", APIKey: "key", ProxyURL: proxy.URL})
+	assert.Nil(t, err)
+	_, err = c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+	assert.Contains(t, proxied, "http://cloudhealth.invalid/v1/perspective_schemas")
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key", Timeout: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	_, err = c.Perspectives.List(context.Background())
+	assert.NotNil(t, err)
+}
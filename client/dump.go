@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// dumpSequence orders dumps written within the same instant
+var dumpSequence uint64
+
+// requestDump is what gets written to Config.DumpDir for every request
+type requestDump struct {
+	Time         string          `json:"time"`
+	Method       string          `json:"method"`
+	URL          string          `json:"url"`
+	Status       int             `json:"status,omitempty"`
+	DurationMs   int64           `json:"duration_ms"`
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// dump writes one request and its response to the dump directory. Failing to
+// write is logged but doesn't fail the request.
+func (c *Client) dump(req *http.Request, start time.Time, status int, responseBody []byte, err error) {
+	if c.dumpDir == "" {
+		return
+	}
+
+	var requestBody []byte
+	if req.GetBody != nil {
+		if body, bodyErr := req.GetBody(); bodyErr == nil {
+			requestBody, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	entry := requestDump{
+		Time:         start.UTC().Format(time.RFC3339Nano),
+		Method:       req.Method,
+		URL:          c.redactor.Redact(req.URL.String()),
+		Status:       status,
+		DurationMs:   time.Since(start).Nanoseconds() / int64(time.Millisecond),
+		RequestBody:  dumpBody(c.redactor.Redact(string(requestBody))),
+		ResponseBody: dumpBody(c.redactor.Redact(string(responseBody))),
+	}
+	if err != nil {
+		entry.Error = c.redactor.Redact(err.Error())
+	}
+
+	data, marshalErr := json.MarshalIndent(entry, "", "  ")
+	if marshalErr != nil {
+		log.Printf("[WARN] Failed to dump CloudHealth %s %s because %s", req.Method, req.URL.Path, marshalErr)
+		return
+	}
+
+	sequence := atomic.AddUint64(&dumpSequence, 1)
+	name := fmt.Sprintf("cht-%s-%06d-%s.json", start.UTC().Format("20060102T150405.000000000Z"), sequence, req.Method)
+	path := filepath.Join(c.dumpDir, name)
+	if writeErr := ioutil.WriteFile(path, data, 0600); writeErr != nil {
+		log.Printf("[WARN] Failed to write CloudHealth request dump %s because %s", path, writeErr)
+	}
+}
+
+// dumpBody keeps JSON bodies as JSON so that dumps are easy to read, and
+// quotes anything else
+func dumpBody(body string) json.RawMessage {
+	if body == "" {
+		return nil
+	}
+	if json.Valid([]byte(body)) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(body)
+	return quoted
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error": "bad key secret-key"}`))
+			return
+		}
+		w.Write([]byte(`not json`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "cht-dump")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dumpDir := filepath.Join(dir, "dumps")
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "secret-key", DumpDir: dumpDir, ClientAPIID: "42"})
+	assert.Nil(t, err)
+
+	pj := new(PerspectiveJSON)
+	pj.Schema.Name = "Dumped"
+	assert.NotNil(t, c.Perspectives.Update(context.Background(), "1", pj))
+	assert.Nil(t, c.Perspectives.Delete(context.Background(), "1", false))
+
+	files, err := filepath.Glob(filepath.Join(dumpDir, "cht-*.json"))
+	assert.Nil(t, err)
+	sort.Strings(files)
+	assert.Equal(t, 2, len(files))
+
+	var put, del map[string]interface{}
+	readDump(t, files[0], &put)
+	readDump(t, files[1], &del)
+
+	assert.Equal(t, "PUT", put["method"])
+	assert.Equal(t, float64(422), put["status"])
+	assert.Equal(t, "Dumped", put["request_body"].(map[string]interface{})["schema"].(map[string]interface{})["name"])
+	assert.Equal(t, "bad key [REDACTED]", put["response_body"].(map[string]interface{})["error"])
+	assert.Contains(t, put["url"], "client_api_id=42")
+	assert.Contains(t, put, "duration_ms")
+
+	assert.Equal(t, "DELETE", del["method"])
+	assert.Equal(t, "not json", del["response_body"])
+	assert.NotContains(t, del, "request_body")
+}
+
+func readDump(t *testing.T, path string, v interface{}) {
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "secret-key")
+	assert.Nil(t, json.Unmarshal(data, v))
+}
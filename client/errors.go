@@ -0,0 +1,187 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIError is returned when CloudHealth responds with a non-2xx status code.
+//
+// CloudHealth usually explains the failure in a JSON body. Whatever could be
+// understood of it is in Message and Details; Body always has the raw text.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+
+	// Message is the overall error reported by CloudHealth, if any
+	Message string
+	// Details lists individual problems, e.g. one per invalid rule
+	Details []APIErrorDetail
+
+	retryAfter time.Duration
+}
+
+// APIErrorDetail is a single problem reported by CloudHealth. Field, when
+// known, is the path of the offending value in the request, for example
+// "rules[2].condition.clauses[0].op".
+type APIErrorDetail struct {
+	Field   string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	for _, detail := range e.Details {
+		if msg != "" {
+			msg += "; "
+		}
+		msg += detail.String()
+	}
+	if msg == "" {
+		msg = e.Body
+	}
+	return fmt.Sprintf("%s %s returned status code %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+func (d APIErrorDetail) String() string {
+	if d.Field == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("%s: %s", d.Field, d.Message)
+}
+
+func newAPIError(req *http.Request, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+	apiErr.Message, apiErr.Details = parseErrorBody(body)
+	return apiErr
+}
+
+// parseErrorBody understands the shapes of error CloudHealth returns:
+//
+//	{"error": "message"}
+//	{"error": {"message": "message", ...}}
+//	{"message": "message"}
+//	{"errors": ["message", ...]}
+//	{"errors": [{"field": "rules[0].asset", "message": "message"}, ...]}
+//	{"errors": {"rules[0].asset": ["message", ...], ...}}
+func parseErrorBody(body []byte) (message string, details []APIErrorDetail) {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Errors  json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", nil
+	}
+
+	message = parsed.Message
+	if len(parsed.Error) > 0 {
+		var s string
+		var obj errorObject
+		if json.Unmarshal(parsed.Error, &s) == nil {
+			message = s
+		} else if json.Unmarshal(parsed.Error, &obj) == nil && obj.message() != "" {
+			message = obj.message()
+		}
+	}
+
+	if len(parsed.Errors) > 0 {
+		details = parseErrorList(parsed.Errors)
+	}
+	return strings.TrimSpace(message), details
+}
+
+func parseErrorList(raw json.RawMessage) (details []APIErrorDetail) {
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		for _, item := range list {
+			var s string
+			var obj errorObject
+			if json.Unmarshal(item, &s) == nil {
+				details = append(details, APIErrorDetail{Message: s})
+			} else if json.Unmarshal(item, &obj) == nil {
+				details = append(details, APIErrorDetail{Field: obj.field(), Message: obj.message()})
+			}
+		}
+		return details
+	}
+
+	// A map of field name to one or more messages
+	var byField map[string]json.RawMessage
+	if json.Unmarshal(raw, &byField) == nil {
+		fields := make([]string, 0, len(byField))
+		for field := range byField {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			var messages []string
+			var s string
+			if json.Unmarshal(byField[field], &messages) == nil {
+				for _, m := range messages {
+					details = append(details, APIErrorDetail{Field: field, Message: m})
+				}
+			} else if json.Unmarshal(byField[field], &s) == nil {
+				details = append(details, APIErrorDetail{Field: field, Message: s})
+			}
+		}
+	}
+	return details
+}
+
+type errorObject struct {
+	Message string `json:"message"`
+	Detail  string `json:"detail"`
+	Error   string `json:"error"`
+	Field   string `json:"field"`
+	Path    string `json:"path"`
+}
+
+func (o errorObject) message() string {
+	for _, m := range []string{o.Message, o.Detail, o.Error} {
+		if m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+func (o errorObject) field() string {
+	if o.Field != "" {
+		return o.Field
+	}
+	return o.Path
+}
+
+// IsArchived returns true if err says the perspective has been archived. It is
+// still there and can be restored, but can no longer be read or updated.
+func IsArchived(err error) bool {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusGone {
+		return true
+	}
+	if apiErr.StatusCode < 400 || apiErr.StatusCode > 499 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message+" "+apiErr.Body), "archived")
+}
+
+// IsNotFound returns true if err says the requested object does not exist.
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound && !IsArchived(err)
+}
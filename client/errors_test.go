@@ -0,0 +1,70 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorBody(t *testing.T) {
+	cases := []struct {
+		body    string
+		message string
+		details []APIErrorDetail
+	}{
+		{`{"error": "Record not found"}`, "Record not found", nil},
+		{`{"error": {"message": "Bad schema"}}`, "Bad schema", nil},
+		{`{"message": "Forbidden"}`, "Forbidden", nil},
+		{`{"errors": ["one", "two"]}`, "", []APIErrorDetail{{Message: "one"}, {Message: "two"}}},
+		{
+			`{"error": "Invalid schema", "errors": [{"field": "rules[2].condition.clauses[1].op", "message": "unknown operator"}]}`,
+			"Invalid schema",
+			[]APIErrorDetail{{Field: "rules[2].condition.clauses[1].op", Message: "unknown operator"}},
+		},
+		{
+			`{"errors": {"rules[1].asset": ["is invalid", "is required"], "name": "is taken"}}`,
+			"",
+			[]APIErrorDetail{
+				{Field: "name", Message: "is taken"},
+				{Field: "rules[1].asset", Message: "is invalid"},
+				{Field: "rules[1].asset", Message: "is required"},
+			},
+		},
+		{`<html>Bad Gateway</html>`, "", nil},
+	}
+
+	for _, c := range cases {
+		message, details := parseErrorBody([]byte(c.body))
+		assert.Equal(t, c.message, message, c.body)
+		assert.Equal(t, c.details, details, c.body)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := &APIError{Method: "PUT", Path: "/v1/perspective_schemas/1", StatusCode: 422, Body: "{}"}
+	assert.Equal(t, "PUT /v1/perspective_schemas/1 returned status code 422: {}", err.Error())
+
+	err.Message = "Invalid schema"
+	err.Details = []APIErrorDetail{{Field: "rules[0].asset", Message: "is invalid"}}
+	assert.Equal(t, "PUT /v1/perspective_schemas/1 returned status code 422: Invalid schema; rules[0].asset: is invalid", err.Error())
+}
+
+func TestIsNotFoundAndArchived(t *testing.T) {
+	notFound := &APIError{StatusCode: 404, Body: `{"error": "Record not found"}`, Message: "Record not found"}
+	gone := &APIError{StatusCode: 410, Body: `{}`}
+	archived := &APIError{StatusCode: 404, Body: `{"error": "Perspective is archived"}`, Message: "Perspective is archived"}
+	serverError := &APIError{StatusCode: 500, Body: `archived`}
+
+	assert.True(t, IsNotFound(notFound))
+	assert.False(t, IsArchived(notFound))
+
+	assert.False(t, IsNotFound(gone))
+	assert.True(t, IsArchived(gone))
+
+	assert.False(t, IsNotFound(archived))
+	assert.True(t, IsArchived(archived))
+
+	assert.False(t, IsNotFound(serverError))
+	assert.False(t, IsArchived(serverError))
+	assert.False(t, IsNotFound(nil))
+}
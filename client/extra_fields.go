@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// extraFieldName is the struct field holding unknown JSON fields
+const extraFieldName = "Extra"
+
+var rawMessageMapType = reflect.TypeOf(map[string]json.RawMessage{})
+
+// jsonFieldNames maps the JSON names of the fields of struct type t to their
+// index
+func jsonFieldNames(t reflect.Type) map[string]int {
+	names := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = i
+	}
+	return names
+}
+
+// captureExtra walks rawData alongside v, which it was decoded into, and
+// stores every JSON field without a matching struct field in the Extra field
+// of the struct it belongs to.
+func captureExtra(rawData []byte, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return captureExtra(rawData, v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(rawData, &items); err != nil {
+			return err
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := captureExtra(items[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rawData, &fields); err != nil {
+		return err
+	}
+	names := jsonFieldNames(v.Type())
+	extra := make(map[string]json.RawMessage)
+	for name, raw := range fields {
+		index, known := names[name]
+		if !known {
+			extra[name] = raw
+			continue
+		}
+		if err := captureExtra(raw, v.Field(index)); err != nil {
+			return err
+		}
+	}
+
+	extraField := v.FieldByName(extraFieldName)
+	if len(extra) > 0 && extraField.IsValid() && extraField.Type() == rawMessageMapType {
+		extraField.Set(reflect.ValueOf(extra))
+	}
+	return nil
+}
+
+// marshalWithExtra marshals v, a struct without a MarshalJSON method, and
+// appends the fields in extra that v doesn't have itself, sorted by name.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	names := jsonFieldNames(reflect.TypeOf(v))
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if _, known := names[key]; !known {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, key := range keys {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
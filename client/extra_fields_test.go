@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const perspectiveWithUnknownFields = `{
+  "schema": {
+    "name": "a",
+    "include_in_reports": "true",
+    "rules": [
+      {
+        "type": "filter",
+        "asset": "AwsAccount",
+        "to": "1",
+        "priority": 3,
+        "condition": {
+          "clauses": [
+            {
+              "field": ["Account Name"],
+              "op": "=",
+              "val": "x",
+              "case_sensitive": false
+            }
+          ],
+          "negate": true
+        }
+      }
+    ],
+    "constants": [
+      {
+        "type": "Static Group",
+        "list": [
+          {
+            "ref_id": "1",
+            "name": "One",
+            "color": "#ff0000"
+          }
+        ],
+        "sorted": true
+      }
+    ],
+    "merges": [],
+    "description": {"text": "new"}
+  }
+}`
+
+func TestDecodePerspectiveRejectsUnknownFields(t *testing.T) {
+	_, err := DecodePerspective([]byte(perspectiveWithUnknownFields))
+	assert.NotNil(t, err)
+}
+
+func TestDecodePerspectiveTolerant(t *testing.T) {
+	pj, err := DecodePerspectiveTolerant([]byte(perspectiveWithUnknownFields))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "a", pj.Schema.Name)
+	assert.Equal(t, map[string]json.RawMessage{"description": json.RawMessage(`{"text": "new"}`)}, pj.Schema.Extra)
+	assert.Equal(t, map[string]json.RawMessage{"priority": json.RawMessage(`3`)}, pj.Schema.Rules[0].Extra)
+	assert.Equal(t, map[string]json.RawMessage{"negate": json.RawMessage(`true`)}, pj.Schema.Rules[0].Condition.Extra)
+	assert.Equal(t, map[string]json.RawMessage{"case_sensitive": json.RawMessage(`false`)}, pj.Schema.Rules[0].Condition.Clauses[0].Extra)
+	assert.Equal(t, map[string]json.RawMessage{"sorted": json.RawMessage(`true`)}, pj.Schema.Constants[0].Extra)
+	assert.Equal(t, map[string]json.RawMessage{"color": json.RawMessage(`"#ff0000"`)}, pj.Schema.Constants[0].List[0].Extra)
+}
+
+func TestDecodePerspectiveTolerantWithoutUnknownFields(t *testing.T) {
+	pj, err := DecodePerspectiveTolerant([]byte(`{"schema": {"name": "a", "include_in_reports": "true", "rules": [{"type": "filter", "to": "1"}]}}`))
+	assert.Nil(t, err)
+	assert.Nil(t, pj.Schema.Extra)
+	assert.Nil(t, pj.Schema.Rules[0].Extra)
+}
+
+func TestUnknownFieldsAreSentBack(t *testing.T) {
+	pj, err := DecodePerspectiveTolerant([]byte(perspectiveWithUnknownFields))
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(pj)
+	assert.Nil(t, err)
+
+	var expected, actual interface{}
+	assert.Nil(t, json.Unmarshal([]byte(perspectiveWithUnknownFields), &expected))
+	assert.Nil(t, json.Unmarshal(data, &actual))
+	assert.Equal(t, expected, actual)
+}
+
+func TestMarshalWithExtraSkipsKnownFields(t *testing.T) {
+	merge := MergeJSON{
+		Type: DynamicGroupType,
+		Extra: map[string]json.RawMessage{
+			"type": json.RawMessage(`"Ignored"`),
+			"new":  json.RawMessage(`1`),
+		},
+	}
+	data, err := json.Marshal(merge)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":"Dynamic Group","new":1}`, string(data))
+
+	data, err = json.Marshal(ClauseJSON{Extra: map[string]json.RawMessage{"new": json.RawMessage(`1`)}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"new":1}`, string(data))
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter caps how fast and how many requests are sent to CloudHealth. It is a
+// token bucket combined with a cap on requests in flight. One Limiter can be
+// shared by several Clients so that they draw from the same quota.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	slots chan struct{}
+}
+
+// NewLimiter returns a Limiter that allows requestsPerSecond requests per
+// second and at most maxConcurrent requests at once. Zero disables either
+// limit.
+func NewLimiter(requestsPerSecond float64, maxConcurrent int) *Limiter {
+	l := &Limiter{
+		rate:  requestsPerSecond,
+		burst: math.Max(1, requestsPerSecond),
+	}
+	l.tokens = l.burst
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// Acquire blocks until a request may be sent. The caller must call release
+// once the request has completed.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l == nil {
+		return release, nil
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		if err := sleepContext(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// reserve takes a token from the bucket and returns how long to wait until
+// that token is actually available.
+func (l *Limiter) reserve() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
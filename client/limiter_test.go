@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(100, 0)
+	start := time.Now()
+	// The bucket starts full with 100 tokens, so 110 requests need ~100ms
+	for i := 0; i < 110; i++ {
+		release, err := l.Acquire(context.Background())
+		assert.Nil(t, err)
+		release()
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond, "took %s", time.Since(start))
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	assert.Nil(t, err)
+	release()
+
+	l = NewLimiter(0, 0)
+	for i := 0; i < 1000; i++ {
+		release, err := l.Acquire(context.Background())
+		assert.Nil(t, err)
+		release()
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter(0, 1)
+	release, err := l.Acquire(context.Background())
+	assert.Nil(t, err)
+
+	// The only slot is taken, so this has to give up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx)
+	assert.NotNil(t, err)
+
+	release()
+	release, err = l.Acquire(context.Background())
+	assert.Nil(t, err)
+	release()
+}
+
+func TestClientRespectsConcurrencyCap(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	// Two clients sharing a limiter share its cap
+	limiter := NewLimiter(0, 2)
+	clients := make([]*Client, 2)
+	for i := range clients {
+		c, err := New(Config{Endpoint: server.URL, APIKey: "key", Limiter: limiter})
+		assert.Nil(t, err)
+		clients[i] = c
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			c.Perspectives.Delete(context.Background(), "1", false)
+		}(clients[i%2])
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maxInFlight)
+}
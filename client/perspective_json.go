@@ -0,0 +1,162 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+type ClauseJSON struct {
+	Field     []string `json:"field,omitempty"`
+	Tag_field []string `json:"tag_field,omitempty"`
+	Op        string   `json:"op,omitempty"`
+	Val       string   `json:"val,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type ConditionJSON struct {
+	Combine_with string       `json:"combine_with,omitempty"`
+	Clauses      []ClauseJSON `json:"clauses,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type RuleJSON struct {
+	Type      string         `json:"type,omitempty"`
+	Asset     string         `json:"asset,omitempty"`
+	To        string         `json:"to,omitempty"`
+	Ref_id    string         `json:"ref_id,omitempty"`    // for type='categorize'
+	Name      string         `json:"name,omitempty"`      // for type='categorize'
+	Field     []string       `json:"field,omitempty"`     // for type='categorize'
+	Tag_field []string       `json:"tag_field,omitempty"` // for type='categorize'
+	Condition *ConditionJSON `json:"condition,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type ConstantItem struct {
+	Ref_id   string  `json:"ref_id,omitempty"`
+	Blk_id   *string `json:"blk_id,omitempty"` // for Dynamic Groups
+	Name     string  `json:"name,omitempty"`
+	Val      string  `json:"val,omitempty"`      // for Dynamic Groups
+	Is_other string  `json:"is_other,omitempty"` // the "Other" for Static Groups
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type ConstantJSON struct {
+	Type string         `json:"type,omitempty"`
+	List []ConstantItem `json:"list,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// MergeJSON folds the constants in From into the constant To, e.g. to combine
+// several dynamic group values into one
+type MergeJSON struct {
+	Type string   `json:"type,omitempty"`
+	To   string   `json:"to,omitempty"`
+	From []string `json:"from,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type SchemaJSON struct {
+	Name               string         `json:"name"`
+	Include_in_reports string         `json:"include_in_reports"`
+	Rules              []RuleJSON     `json:"rules"`
+	Constants          []ConstantJSON `json:"constants"`
+	Merges             []MergeJSON    `json:"merges"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// PerspectiveJSON is the perspective schema sent to and returned by the API.
+//
+// The Extra field of each part holds the fields the API returned that this
+// package doesn't know about, when decoded with DecodePerspectiveTolerant.
+// They are written back as they were when encoding.
+type PerspectiveJSON struct {
+	Schema SchemaJSON `json:"schema"`
+}
+
+const StaticGroupType = "Static Group"
+const DynamicGroupType = "Dynamic Group"
+const DynamicGroupBlockType = "Dynamic Group Block"
+
+func NewConstantJSON(t string) (constant *ConstantJSON) {
+	constant = new(ConstantJSON)
+	constant.Type = t
+	constant.List = make([]ConstantItem, 0)
+	return constant
+}
+
+// DecodePerspective parses a perspective schema as returned by the API. It
+// errors on any field that isn't part of PerspectiveJSON, see
+// DecodePerspectiveTolerant for keeping them instead.
+func DecodePerspective(rawData []byte) (*PerspectiveJSON, error) {
+	var pj PerspectiveJSON
+
+	var jsonHandle codec.JsonHandle
+	jsonHandle.ErrorIfNoField = true
+
+	var dec *codec.Decoder = codec.NewDecoderBytes(rawData, &jsonHandle)
+	err := dec.Decode(&pj)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse json for perspective because %s", err)
+	}
+	return &pj, nil
+}
+
+// DecodePerspectiveTolerant parses a perspective schema as returned by the API.
+// Fields that aren't part of PerspectiveJSON are kept in the Extra field of
+// the part they were found in, so that a newer API doesn't break decoding.
+func DecodePerspectiveTolerant(rawData []byte) (*PerspectiveJSON, error) {
+	var pj PerspectiveJSON
+	err := json.Unmarshal(rawData, &pj)
+	if err == nil {
+		err = captureExtra(rawData, reflect.ValueOf(&pj).Elem())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse json for perspective because %s", err)
+	}
+	return &pj, nil
+}
+
+func (c ClauseJSON) MarshalJSON() ([]byte, error) {
+	type clause ClauseJSON
+	return marshalWithExtra(clause(c), c.Extra)
+}
+
+func (c ConditionJSON) MarshalJSON() ([]byte, error) {
+	type condition ConditionJSON
+	return marshalWithExtra(condition(c), c.Extra)
+}
+
+func (r RuleJSON) MarshalJSON() ([]byte, error) {
+	type rule RuleJSON
+	return marshalWithExtra(rule(r), r.Extra)
+}
+
+func (c ConstantItem) MarshalJSON() ([]byte, error) {
+	type item ConstantItem
+	return marshalWithExtra(item(c), c.Extra)
+}
+
+func (c ConstantJSON) MarshalJSON() ([]byte, error) {
+	type constant ConstantJSON
+	return marshalWithExtra(constant(c), c.Extra)
+}
+
+func (m MergeJSON) MarshalJSON() ([]byte, error) {
+	type merge MergeJSON
+	return marshalWithExtra(merge(m), m.Extra)
+}
+
+func (s SchemaJSON) MarshalJSON() ([]byte, error) {
+	type schema SchemaJSON
+	return marshalWithExtra(schema(s), s.Extra)
+}
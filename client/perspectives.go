@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const perspectiveSchemasPath = "v1/perspective_schemas"
+
+// perspectiveListPageSize is how many perspectives List asks for at once
+const perspectiveListPageSize = 100
+
+var createdRegexp = regexp.MustCompile(`Perspective (\d*) created`)
+
+// PerspectiveService wraps the /v1/perspective_schemas endpoints.
+type PerspectiveService struct {
+	client *Client
+}
+
+// PerspectiveSummary is one entry of the perspective list endpoint.
+type PerspectiveSummary struct {
+	ID     string
+	Name   string
+	Active bool
+}
+
+func perspectivePath(id string) string {
+	return fmt.Sprintf("%s/%s", perspectiveSchemasPath, url.PathEscape(id))
+}
+
+// Get loads the schema of the perspective with the given ID.
+func (s *PerspectiveService) Get(ctx context.Context, id string) (*PerspectiveJSON, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, perspectivePath(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.client.keepUnknownFields {
+		return DecodePerspectiveTolerant(body)
+	}
+	return DecodePerspective(body)
+}
+
+// Create creates a new perspective and returns its ID.
+func (s *PerspectiveService) Create(ctx context.Context, pj *PerspectiveJSON) (string, error) {
+	payload, err := json.Marshal(pj)
+	if err != nil {
+		return "", err
+	}
+	req, err := s.client.newRequest(ctx, http.MethodPost, perspectiveSchemasPath, nil, payload)
+	if err != nil {
+		return "", err
+	}
+	body, err := s.client.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	match := createdRegexp.FindStringSubmatch(string(body))
+	if match == nil || len(match) != 2 || match[1] == "" {
+		return "", fmt.Errorf("Created perspective but didn't understand response to extract ID: %s", body)
+	}
+	return match[1], nil
+}
+
+// Update replaces the schema of the perspective with the given ID.
+func (s *PerspectiveService) Update(ctx context.Context, id string, pj *PerspectiveJSON) error {
+	payload, err := json.Marshal(pj)
+	if err != nil {
+		return err
+	}
+	req, err := s.client.newRequest(ctx, http.MethodPut, perspectivePath(id), nil, payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do(req)
+	return err
+}
+
+// Delete archives the perspective with the given ID, or removes it entirely
+// if hardDelete is set.
+func (s *PerspectiveService) Delete(ctx context.Context, id string, hardDelete bool) error {
+	query := url.Values{}
+	query.Set("hard_delete", strconv.FormatBool(hardDelete))
+	req, err := s.client.newRequest(ctx, http.MethodDelete, perspectivePath(id), query, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do(req)
+	return err
+}
+
+// List returns every perspective in the account, ordered by ID. Large
+// accounts are fetched a page at a time.
+func (s *PerspectiveService) List(ctx context.Context) ([]PerspectiveSummary, error) {
+	result := make([]PerspectiveSummary, 0)
+	seen := make(map[string]bool)
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(perspectiveListPageSize))
+		req, err := s.client.newRequest(ctx, http.MethodGet, perspectiveSchemasPath, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := s.client.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// The list endpoint returns a map of ID to summary
+		var byID map[string]struct {
+			Name   string `json:"name"`
+			Active bool   `json:"active"`
+		}
+		if err := json.Unmarshal(body, &byID); err != nil {
+			return nil, fmt.Errorf("Unable to parse perspective list because %s", err)
+		}
+
+		added := 0
+		for id, summary := range byID {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			added++
+			result = append(result, PerspectiveSummary{
+				ID:     id,
+				Name:   summary.Name,
+				Active: summary.Active,
+			})
+		}
+		// A short page is the last one. Stop too if the endpoint ignored the
+		// page and sent perspectives already seen.
+		if len(byID) < perspectiveListPageSize || added == 0 {
+			break
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, errA := strconv.Atoi(result[i].ID)
+		b, errB := strconv.Atoi(result[j].ID)
+		if errA != nil || errB != nil {
+			return result[i].ID < result[j].ID
+		}
+		return a < b
+	})
+	return result, nil
+}
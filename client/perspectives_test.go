@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(Config{
+		Endpoint: server.URL,
+		APIKey:   "secret-key",
+	})
+	assert.Nil(t, err)
+	return c
+}
+
+func TestGetPerspective(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/perspective_schemas/1234", r.URL.Path)
+		assert.Equal(t, "Bearer secret-key", r.Header.Get("Authorization"))
+		assert.Empty(t, r.URL.Query().Get("api_key"))
+		http.ServeFile(w, r, "../test/static_perspective.json")
+	})
+
+	pj, err := c.Perspectives.Get(context.Background(), "1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "My Name", pj.Schema.Name)
+	assert.Equal(t, 3, len(pj.Schema.Rules))
+	assert.Equal(t, StaticGroupType, pj.Schema.Constants[0].Type)
+}
+
+func TestGetPerspectiveUnrecognizedData(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"schema": {"name": "a", "include_in_reports": "true", "some_key": "some_value"}}`))
+	})
+
+	_, err := c.Perspectives.Get(context.Background(), "1234")
+	assert.NotNil(t, err)
+}
+
+func TestGetPerspectiveNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Record not found"}`))
+	})
+
+	_, err := c.Perspectives.Get(context.Background(), "1234")
+	apiErr, ok := err.(*APIError)
+	assert.True(t, ok, "error is an *APIError")
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, `{"error": "Record not found"}`, apiErr.Body)
+}
+
+func TestCreatePerspective(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/perspective_schemas", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var pj PerspectiveJSON
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(body, &pj))
+		assert.Equal(t, "New Perspective", pj.Schema.Name)
+
+		w.Write([]byte(`{"message": "Perspective 5678 created"}`))
+	})
+
+	pj := new(PerspectiveJSON)
+	pj.Schema.Name = "New Perspective"
+	id, err := c.Perspectives.Create(context.Background(), pj)
+	assert.Nil(t, err)
+	assert.Equal(t, "5678", id)
+}
+
+func TestCreatePerspectiveUnexpectedResponse(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": "Something happened"}`))
+	})
+
+	_, err := c.Perspectives.Create(context.Background(), new(PerspectiveJSON))
+	assert.NotNil(t, err)
+}
+
+func TestUpdatePerspective(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/v1/perspective_schemas/1234", r.URL.Path)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error": "Bad rule"}`))
+	})
+
+	err := c.Perspectives.Update(context.Background(), "1234", new(PerspectiveJSON))
+	apiErr, ok := err.(*APIError)
+	assert.True(t, ok, "error is an *APIError")
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+}
+
+func TestDeletePerspective(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/v1/perspective_schemas/1234", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("hard_delete"))
+	})
+
+	err := c.Perspectives.Delete(context.Background(), "1234", true)
+	assert.Nil(t, err)
+}
+
+func TestListPerspectives(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/perspective_schemas", r.URL.Path)
+		w.Write([]byte(`{
+			"20": {"name": "Twenty", "active": false},
+			"3": {"name": "Three", "active": true}
+		}`))
+	})
+
+	perspectives, err := c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []PerspectiveSummary{
+		{ID: "3", Name: "Three", Active: true},
+		{ID: "20", Name: "Twenty", Active: false},
+	}, perspectives)
+}
+
+func TestClientAPIID(t *testing.T) {
+	var tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants = append(tenants, r.URL.Query().Get("client_api_id"))
+		assert.Equal(t, "true", r.URL.Query().Get("hard_delete"))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key", ClientAPIID: "100"})
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Perspectives.Delete(context.Background(), "1", true))
+	assert.Nil(t, c.WithClientAPIID("200").Perspectives.Delete(context.Background(), "1", true))
+	assert.Nil(t, c.WithClientAPIID("").Perspectives.Delete(context.Background(), "1", true))
+	assert.Equal(t, []string{"100", "200", "100"}, tenants)
+}
+
+func TestGetPerspectiveKeepUnknownFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"schema": {"name": "a", "include_in_reports": "true", "some_key": "some_value"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(Config{
+		Endpoint:          server.URL,
+		APIKey:            "secret-key",
+		KeepUnknownFields: true,
+	})
+	assert.Nil(t, err)
+
+	pj, err := c.Perspectives.Get(context.Background(), "1234")
+	assert.Nil(t, err)
+	assert.Equal(t, json.RawMessage(`"some_value"`), pj.Schema.Extra["some_key"])
+}
+
+func TestListPerspectivesPages(t *testing.T) {
+	var pages []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+
+		byID := make(map[string]interface{})
+		start := map[string]int{"1": 1, "2": 101}[page]
+		count := map[string]int{"1": 100, "2": 3}[page]
+		for id := start; id < start+count; id++ {
+			byID[strconv.Itoa(id)] = map[string]interface{}{"name": "P" + strconv.Itoa(id), "active": true}
+		}
+		json.NewEncoder(w).Encode(byID)
+	})
+
+	perspectives, err := c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2"}, pages)
+	assert.Equal(t, 103, len(perspectives))
+	assert.Equal(t, "103", perspectives[102].ID)
+}
+
+func TestListPerspectivesPageIgnored(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		byID := make(map[string]interface{})
+		for id := 1; id <= 100; id++ {
+			byID[strconv.Itoa(id)] = map[string]interface{}{"name": "P", "active": true}
+		}
+		json.NewEncoder(w).Encode(byID)
+	})
+
+	perspectives, err := c.Perspectives.List(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 100, len(perspectives))
+}
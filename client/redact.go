@@ -0,0 +1,94 @@
+package client
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const redactedText = "[REDACTED]"
+
+// Redactor scrubs secrets such as the API key out of anything that may be
+// logged or shown to a user. It is safe for concurrent use.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// Add registers another secret to scrub. Empty strings are ignored.
+func (r *Redactor) Add(secret string) {
+	if secret == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.secrets {
+		if s == secret {
+			return
+		}
+	}
+	r.secrets = append(r.secrets, secret)
+	// Also catch the secret once it has been escaped into a URL
+	if escaped := url.QueryEscape(secret); escaped != secret {
+		r.secrets = append(r.secrets, escaped)
+	}
+}
+
+// Redact returns s with every registered secret replaced.
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, redactedText, -1)
+	}
+	return s
+}
+
+// RedactError returns an error whose message has been scrubbed. The original
+// error is still available to errors.Is and errors.As.
+func (r *Redactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if apiErr, ok := err.(*APIError); ok {
+		apiErr.Body = r.Redact(apiErr.Body)
+		apiErr.Message = r.Redact(apiErr.Message)
+		for i := range apiErr.Details {
+			apiErr.Details[i].Message = r.Redact(apiErr.Details[i].Message)
+		}
+		return apiErr
+	}
+	msg := err.Error()
+	redacted := r.Redact(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+// Writer wraps w so that everything written to it is scrubbed first. It is
+// meant to be used with log.SetOutput.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &redactingWriter{redactor: r, w: w}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+type redactingWriter struct {
+	redactor *Redactor
+	w        io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
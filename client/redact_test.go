@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	r := new(Redactor)
+	r.Add("s3cr3t/key")
+	r.Add("")
+
+	assert.Equal(t, "key is [REDACTED]", r.Redact("key is s3cr3t/key"))
+	assert.Equal(t, "url is /?api_key=[REDACTED]", r.Redact("url is /?api_key=s3cr3t%2Fkey"))
+	assert.Equal(t, "nothing to see", r.Redact("nothing to see"))
+}
+
+func TestRedactError(t *testing.T) {
+	r := new(Redactor)
+	r.Add("secret-key")
+
+	urlErr := &url.Error{Op: "Get", URL: "https://example.com/?api_key=secret-key", Err: errors.New("timeout")}
+	err := r.RedactError(urlErr)
+	assert.NotContains(t, err.Error(), "secret-key")
+
+	var unwrapped *url.Error
+	assert.True(t, errors.As(err, &unwrapped))
+
+	plain := errors.New("harmless")
+	assert.Equal(t, plain, r.RedactError(plain))
+	assert.Nil(t, r.RedactError(nil))
+}
+
+func TestRedactWriter(t *testing.T) {
+	r := new(Redactor)
+	r.Add("secret-key")
+
+	var buf bytes.Buffer
+	logger := log.New(r.Writer(&buf), "", 0)
+	logger.Printf("calling with secret-key")
+	assert.Equal(t, "calling with [REDACTED]\n", buf.String())
+}
+
+func TestAPIErrorIsRedacted(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "Invalid key secret-key"}`))
+	})
+
+	_, err := c.Perspectives.Get(context.Background(), "1234")
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "secret-key")
+}
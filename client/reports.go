@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ReportService wraps the OLAP report endpoints, /olap_reports/<report>.
+type ReportService struct {
+	client *Client
+}
+
+// ReportQuery selects what an OLAP report returns.
+type ReportQuery struct {
+	// Report is the path of the report under /olap_reports, e.g.
+	// cost/history
+	Report string
+	// Interval is the time granularity, e.g. monthly or daily
+	Interval string
+	// Dimensions to break the report down by, in order. A perspective is a
+	// dimension named by its ID.
+	Dimensions []string
+	// Measures to report, e.g. cost
+	Measures []string
+	// Filters in CloudHealth's syntax, e.g. time:select:2024-01,2024-02
+	Filters []string
+}
+
+// ReportMember is one value of a dimension, e.g. a month or a perspective
+// group. Each dimension starts with a member for the total.
+type ReportMember struct {
+	Name         string `json:"name"`
+	Label        string `json:"label"`
+	ExtendedName string `json:"extended_name"`
+	Parent       int    `json:"parent"`
+}
+
+// ReportDimension lists the members of one dimension, in the order of the
+// report's data.
+type ReportDimension struct {
+	Name    string
+	Members []ReportMember
+}
+
+// ReportMeasure describes one measure of the report.
+type ReportMeasure struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// Report is the result of an OLAP report query.
+type Report struct {
+	Dimensions []ReportDimension
+	Measures   []ReportMeasure
+	// Data is indexed by the member of each dimension in turn, then by
+	// measure. Use Value to read it.
+	Data []interface{}
+}
+
+// Value returns a measure for the given member of each dimension. ok is
+// false when CloudHealth has no value there.
+func (r *Report) Value(measure int, members ...int) (value float64, ok bool) {
+	var cell interface{} = r.Data
+	for _, idx := range append(members, measure) {
+		list, isList := cell.([]interface{})
+		if !isList || idx < 0 || idx >= len(list) {
+			return 0, false
+		}
+		cell = list[idx]
+	}
+	value, ok = cell.(float64)
+	return value, ok
+}
+
+// IsTotal reports whether the member is the total of its dimension.
+func (m ReportMember) IsTotal() bool {
+	return strings.EqualFold(m.Name, "total")
+}
+
+// Get runs a report query.
+func (s *ReportService) Get(ctx context.Context, q ReportQuery) (*Report, error) {
+	query := url.Values{}
+	if q.Interval != "" {
+		query.Set("interval", q.Interval)
+	}
+	for _, dimension := range q.Dimensions {
+		query.Add("dimensions[]", dimension)
+	}
+	for _, measure := range q.Measures {
+		query.Add("measures[]", measure)
+	}
+	for _, filter := range q.Filters {
+		query.Add("filters[]", filter)
+	}
+	req, err := s.client.newRequest(ctx, http.MethodGet, "olap_reports/"+strings.Trim(q.Report, "/"), query, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each dimension comes as an object with its name as the only key
+	var raw struct {
+		Dimensions []map[string][]ReportMember `json:"dimensions"`
+		Measures   []ReportMeasure             `json:"measures"`
+		Data       []interface{}               `json:"data"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("Unable to parse report %s because %s", q.Report, err)
+	}
+
+	report := &Report{Measures: raw.Measures, Data: raw.Data}
+	for _, dimension := range raw.Dimensions {
+		if len(dimension) != 1 {
+			return nil, fmt.Errorf("Unable to parse report %s because a dimension has %d names", q.Report, len(dimension))
+		}
+		for name, members := range dimension {
+			report.Dimensions = append(report.Dimensions, ReportDimension{Name: name, Members: members})
+		}
+	}
+	return report, nil
+}
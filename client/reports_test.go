@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const costReport = `{
+	"report": "AWS Cost History",
+	"dimensions": [
+		{"time": [
+			{"name": "total", "label": "Total", "parent": -1},
+			{"name": "2024-01", "label": "Jan 2024", "parent": -1},
+			{"name": "2024-02", "label": "Feb 2024", "parent": -1}
+		]},
+		{"1234": [
+			{"name": "total", "label": "Total", "parent": -1},
+			{"name": "2199023255553", "label": "Platform", "parent": -1},
+			{"name": "2199023255554", "label": "Other", "parent": -1}
+		]}
+	],
+	"measures": [{"name": "cost", "label": "Cost ($)"}],
+	"interval": "monthly",
+	"data": [
+		[[30.5], [20.5], [10.0]],
+		[[10.25], [10.25], [null]],
+		[[20.25], [10.25], [10.0]]
+	]
+}`
+
+func TestGetReport(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/olap_reports/cost/history", r.URL.Path)
+		query := r.URL.Query()
+		assert.Equal(t, "monthly", query.Get("interval"))
+		assert.Equal(t, []string{"time", "1234"}, query["dimensions[]"])
+		assert.Equal(t, []string{"cost"}, query["measures[]"])
+		assert.Equal(t, []string{"time:select:2024-01,2024-02"}, query["filters[]"])
+		w.Write([]byte(costReport))
+	})
+
+	report, err := c.Reports.Get(context.Background(), ReportQuery{
+		Report:     "cost/history",
+		Interval:   "monthly",
+		Dimensions: []string{"time", "1234"},
+		Measures:   []string{"cost"},
+		Filters:    []string{"time:select:2024-01,2024-02"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(report.Dimensions))
+	assert.Equal(t, "time", report.Dimensions[0].Name)
+	assert.Equal(t, "1234", report.Dimensions[1].Name)
+	assert.True(t, report.Dimensions[1].Members[0].IsTotal())
+	assert.Equal(t, "Platform", report.Dimensions[1].Members[1].Label)
+	assert.Equal(t, "cost", report.Measures[0].Name)
+
+	value, ok := report.Value(0, 2, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 10.25, value)
+	_, ok = report.Value(0, 1, 2)
+	assert.False(t, ok, "null has no value")
+	_, ok = report.Value(0, 3, 1)
+	assert.False(t, ok, "out of range has no value")
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryBaseWait is the wait before the first retry. It doubles on each
+// subsequent attempt, up to Config.RetryMaxWait.
+const retryBaseWait = 500 * time.Millisecond
+
+// shouldRetry decides if a failed request can safely be sent again.
+//
+// GET, PUT and DELETE are idempotent, so they are retried on throttling,
+// server errors and network errors. A POST creates a perspective, so it is
+// only retried when we know CloudHealth did not act on it: when it was
+// throttled with a 429, or when we never managed to connect.
+func shouldRetry(req *http.Request, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := req.Method != http.MethodPost
+
+	if apiErr, ok := err.(*APIError); ok {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return true
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return idempotent
+		default:
+			return false
+		}
+	}
+
+	if idempotent {
+		return true
+	}
+	return isDialError(err)
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryWait returns how long to wait before the given retry attempt (counting
+// from 0). A Retry-After from the server is honoured, but never beyond maxWait.
+func retryWait(attempt int, err error, maxWait time.Duration) time.Duration {
+	if apiErr, ok := err.(*APIError); ok && apiErr.retryAfter > 0 {
+		if apiErr.retryAfter > maxWait {
+			return maxWait
+		}
+		return apiErr.retryAfter
+	}
+
+	backoff := retryBaseWait << uint(attempt)
+	if backoff > maxWait || backoff <= 0 {
+		backoff = maxWait
+	}
+	// Jitter so that parallel requests throttled together don't retry together
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a number
+// of seconds, or an HTTP date.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rewind returns a copy of req that can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newRetryingTestClient returns a client that retries up to 3 times and a
+// pointer to the count of requests the server received.
+func newRetryingTestClient(t *testing.T, statuses ...int) (*Client, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message": "Perspective 1 created"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(Config{
+		Endpoint:     server.URL,
+		APIKey:       "key",
+		MaxRetries:   3,
+		RetryMaxWait: time.Millisecond,
+	})
+	assert.Nil(t, err)
+	return c, &calls
+}
+
+func TestRetryIdempotentOnServerError(t *testing.T) {
+	c, calls := newRetryingTestClient(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+	err := c.Perspectives.Delete(context.Background(), "1", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	c, calls := newRetryingTestClient(t, 500, 500, 500, 500, 500)
+	err := c.Perspectives.Delete(context.Background(), "1", false)
+	assert.Equal(t, http.StatusInternalServerError, err.(*APIError).StatusCode)
+	assert.Equal(t, 4, *calls)
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	c, calls := newRetryingTestClient(t, http.StatusUnprocessableEntity)
+	err := c.Perspectives.Delete(context.Background(), "1", false)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestRetryCreateOnlyWhenThrottled(t *testing.T) {
+	c, calls := newRetryingTestClient(t, http.StatusTooManyRequests)
+	id, err := c.Perspectives.Create(context.Background(), new(PerspectiveJSON))
+	assert.Nil(t, err)
+	assert.Equal(t, "1", id)
+	assert.Equal(t, 2, *calls)
+
+	// The perspective may have been created, so a server error isn't retried
+	c, calls = newRetryingTestClient(t, http.StatusBadGateway)
+	_, err = c.Perspectives.Create(context.Background(), new(PerspectiveJSON))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestRetryResendsBody(t *testing.T) {
+	var bodies []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, r.ContentLength)
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key", MaxRetries: 1})
+	assert.Nil(t, err)
+	err = c.Perspectives.Update(context.Background(), "1", new(PerspectiveJSON))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bodies))
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.NotZero(t, bodies[1])
+}
+
+func TestRetryWait(t *testing.T) {
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests, retryAfter: 2 * time.Second}
+	assert.Equal(t, 2*time.Second, retryWait(0, throttled, time.Minute))
+	assert.Equal(t, time.Second, retryWait(0, throttled, time.Second))
+
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := retryWait(attempt, &APIError{StatusCode: 503}, 10*time.Second)
+		backoff := retryBaseWait << uint(attempt)
+		if backoff > 10*time.Second {
+			backoff = 10 * time.Second
+		}
+		assert.True(t, wait >= backoff/2 && wait <= backoff, "attempt %d waited %s", attempt, wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+
+	later := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	assert.True(t, parseRetryAfter(later) > 59*time.Minute)
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c, err := New(Config{Endpoint: server.URL, APIKey: "key", MaxRetries: 5, RetryMaxWait: time.Hour})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.Perspectives.Get(ctx, "1")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
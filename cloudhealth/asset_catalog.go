@@ -0,0 +1,50 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"sync"
+)
+
+// assetCatalog caches the asset metadata for the life of the provider, so
+// that the data sources and plan-time checks of every perspective load each
+// part of it only once. Failed loads are not cached.
+type assetCatalog struct {
+	mu     sync.Mutex
+	types  []string
+	byName map[string]*client.AssetType
+}
+
+// assetTypes returns the names of every asset type, sorted
+func (m *ChtMeta) assetTypes(ctx context.Context) ([]string, error) {
+	m.assets.mu.Lock()
+	defer m.assets.mu.Unlock()
+
+	if m.assets.types == nil {
+		types, err := m.client.Assets.Types(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.assets.types = types
+	}
+	return m.assets.types, nil
+}
+
+// assetType returns the attributes and relations of an asset type
+func (m *ChtMeta) assetType(ctx context.Context, name string) (*client.AssetType, error) {
+	m.assets.mu.Lock()
+	defer m.assets.mu.Unlock()
+
+	if assetType, ok := m.assets.byName[name]; ok {
+		return assetType, nil
+	}
+	assetType, err := m.client.Assets.Describe(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if m.assets.byName == nil {
+		m.assets.byName = make(map[string]*client.AssetType)
+	}
+	m.assets.byName[name] = assetType
+	return assetType, nil
+}
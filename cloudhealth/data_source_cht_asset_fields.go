@@ -0,0 +1,92 @@
+package cloudhealth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceCHTAssetFields() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTAssetFieldsRead,
+
+		Schema: map[string]*schema.Schema{
+			// Asset type, e.g. AwsAccount
+			"asset": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			// In the order CloudHealth lists them
+			"attributes": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": computedString(),
+						"kind": computedString(),
+						"nullable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+					},
+				},
+			},
+			// Other asset types this one links to
+			"relations": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": computedString(),
+						"kind": computedString(),
+					},
+				},
+			},
+			// Names of the attributes
+			"names": computedStringList(),
+		},
+	}
+}
+
+func dataSourceCHTAssetFieldsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	asset := d.Get("asset").(string)
+	assetType, err := meta.(*ChtMeta).assetType(ctx, asset)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to describe asset type %s", asset), err, nil)
+	}
+
+	attributes := make([]interface{}, 0)
+	names := make([]string, 0)
+	for _, attribute := range assetType.Attributes {
+		attributes = append(attributes, map[string]interface{}{
+			"name":     attribute.Name,
+			"kind":     attribute.Kind,
+			"nullable": attribute.Nullable,
+		})
+		names = append(names, attribute.Name)
+	}
+	relations := make([]interface{}, 0)
+	for _, relation := range assetType.Relations {
+		relations = append(relations, map[string]interface{}{
+			"name": relation.Name,
+			"kind": relation.Kind,
+		})
+	}
+
+	d.SetId(asset)
+	for attr, value := range map[string]interface{}{
+		"attributes": attributes,
+		"relations":  relations,
+		"names":      names,
+	} {
+		err = d.Set(attr, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cloudhealth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataSourceAssetFields(t *testing.T) {
+	requests := make(map[string]int)
+	meta := newTestMeta(t, assetHandler(requests))
+
+	dataSource := dataSourceCHTAssetFields()
+	rd := dataSource.TestResourceData()
+	rd.Set("asset", "AwsAccount")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, "AwsAccount", rd.Id())
+	assertEqual(t, rd, "names", []interface{}{"id", "name"})
+	assertEqual(t, rd, "attributes.0.kind", "integer")
+	assertEqual(t, rd, "attributes.0.nullable", false)
+	assertEqual(t, rd, "attributes.1.nullable", true)
+	assertEqual(t, rd, "relations.0.name", "billing_account")
+	assertEqual(t, rd, "relations.0.kind", "AwsAccount")
+}
+
+func TestDataSourceAssetFieldsUnknownAsset(t *testing.T) {
+	meta := newTestMeta(t, assetHandler(make(map[string]int)))
+
+	dataSource := dataSourceCHTAssetFields()
+	rd := dataSource.TestResourceData()
+	rd.Set("asset", "Missing")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "Failed to describe asset type Missing", diags[0].Summary)
+}
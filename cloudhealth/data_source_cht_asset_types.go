@@ -0,0 +1,33 @@
+package cloudhealth
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceCHTAssetTypes() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTAssetTypesRead,
+
+		Schema: map[string]*schema.Schema{
+			// Sorted
+			"names": computedStringList(),
+		},
+	}
+}
+
+func dataSourceCHTAssetTypesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	names, err := meta.(*ChtMeta).assetTypes(ctx)
+	if err != nil {
+		return apiErrorDiagnostics("Failed to list asset types", err, nil)
+	}
+
+	d.SetId("asset_types")
+	err = d.Set("names", names)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
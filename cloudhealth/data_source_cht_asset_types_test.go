@@ -0,0 +1,47 @@
+package cloudhealth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assetHandler serves an asset catalog of AwsAccount and AwsInstance, counting
+// the requests for each path
+func assetHandler(requests map[string]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/api.json":
+			w.Write([]byte(`{"list": ["AwsInstance", "AwsAccount"]}`))
+		case "/api/AwsAccount.json":
+			w.Write([]byte(`{
+				"name": "AwsAccount",
+				"attributes": [
+					{"name": "id", "kind": "integer", "nullable": false},
+					{"name": "name", "kind": "string", "nullable": true}
+				],
+				"relations": [{"name": "billing_account", "kind": "AwsAccount"}]
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Record not found"}`))
+		}
+	}
+}
+
+func TestDataSourceAssetTypes(t *testing.T) {
+	requests := make(map[string]int)
+	meta := newTestMeta(t, assetHandler(requests))
+
+	dataSource := dataSourceCHTAssetTypes()
+	for i := 0; i < 2; i++ {
+		rd := dataSource.TestResourceData()
+		diags := dataSource.ReadContext(context.Background(), rd, meta)
+		assert.Equal(t, 0, len(diags), "%v", diags)
+		assertEqual(t, rd, "names", []interface{}{"AwsAccount", "AwsInstance"})
+	}
+	assert.Equal(t, 1, requests["/api.json"], "the catalog is cached")
+}
@@ -0,0 +1,128 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceCHTAssets() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTAssetsRead,
+
+		Schema: map[string]*schema.Schema{
+			// Asset type to search, e.g. AwsInstance
+			"name": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			// CloudHealth search query, e.g. is_active=1
+			"query": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// Attributes to return. All of them when not set.
+			"fields": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+			},
+			"page_size": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      client.DefaultAssetSearchPageSize,
+				ValidateFunc: validation.IntBetween(1, client.DefaultAssetSearchPageSize),
+			},
+			// Maximum number of assets to return. 0 means all.
+			"limit": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			// Overrides the provider's client_api_id
+			"client_api_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// Values that aren't strings are JSON encoded
+			"assets": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeMap,
+					Elem: &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceCHTAssetsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+
+	search := client.AssetSearch{
+		Name:     d.Get("name").(string),
+		Query:    d.Get("query").(string),
+		PageSize: d.Get("page_size").(int),
+		Limit:    d.Get("limit").(int),
+	}
+	for _, field := range d.Get("fields").([]interface{}) {
+		search.Fields = append(search.Fields, field.(string))
+	}
+
+	found, err := c.Assets.Search(ctx, search)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to search %s assets", search.Name), err, nil)
+	}
+
+	assets := make([]interface{}, 0, len(found))
+	for _, asset := range found {
+		values, err := assetValues(asset)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		assets = append(assets, values)
+	}
+
+	// The same search always gives the same ID
+	d.SetId(fmt.Sprintf("%s/%s/%s/%s", d.Get("client_api_id"), search.Name, search.Query, strings.Join(search.Fields, ",")))
+
+	err = d.Set("assets", assets)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// assetValues converts a search result to a map of strings. Strings are kept
+// as they are, null becomes "" and everything else is JSON encoded, so that
+// nested values can be read back with jsondecode.
+func assetValues(asset map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(asset))
+	for k, v := range asset {
+		switch v := v.(type) {
+		case nil:
+			values[k] = ""
+		case string:
+			values[k] = v
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to encode %s of asset because %s", k, err)
+			}
+			values[k] = string(encoded)
+		}
+	}
+	return values, nil
+}
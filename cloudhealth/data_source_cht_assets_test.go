@@ -0,0 +1,55 @@
+package cloudhealth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataSourceAssets(t *testing.T) {
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, "AwsInstance", r.URL.Query().Get("name"))
+		assert.Equal(t, "tags.team='x'", r.URL.Query().Get("query"))
+		assert.Equal(t, "9876", r.URL.Query().Get("client_api_id"))
+		w.Write([]byte(`[{
+			"name": "web-1",
+			"id": 12345678901234567,
+			"is_active": true,
+			"owner_id": null,
+			"tags": [{"key": "team", "value": "x"}]
+		}]`))
+	})
+
+	dataSource := dataSourceCHTAssets()
+	rd := dataSource.TestResourceData()
+	rd.Set("name", "AwsInstance")
+	rd.Set("query", "tags.team='x'")
+	rd.Set("client_api_id", "9876")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, "9876/AwsInstance/tags.team='x'/", rd.Id())
+	assertEqual(t, rd, "assets.#", 1)
+	assertEqual(t, rd, "assets.0.name", "web-1")
+	assertEqual(t, rd, "assets.0.id", "12345678901234567")
+	assertEqual(t, rd, "assets.0.is_active", "true")
+	assertEqual(t, rd, "assets.0.owner_id", "")
+	assertEqual(t, rd, "assets.0.tags", `[{"key":"team","value":"x"}]`)
+}
+
+func TestDataSourceAssetsNoMatches(t *testing.T) {
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+
+	dataSource := dataSourceCHTAssets()
+	rd := dataSource.TestResourceData()
+	rd.Set("name", "AwsInstance")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assertEqual(t, rd, "assets", []interface{}{})
+}
@@ -0,0 +1,288 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Layout of the time members of a report, by interval
+var periodLayouts = map[string]string{
+	"monthly": "2006-01",
+	"daily":   "2006-01-02",
+}
+
+func dataSourceCHTCostReport() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTCostReportRead,
+
+		Schema: map[string]*schema.Schema{
+			// The perspective to break the cost down by
+			"perspective_id": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			"interval": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "monthly",
+				ValidateDiagFunc: stringInSlice([]string{"monthly", "daily"}),
+			},
+			// First and last period of the report, e.g. 2024-01 when monthly
+			// or 2024-01-31 when daily
+			"start": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"end": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			// The OLAP report, under /olap_reports
+			"report": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "cost/history",
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			"measure": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "cost",
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			// Overrides the provider's client_api_id
+			"client_api_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// In the order of the report
+			"groups": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						// The name of the group in the report
+						"member_id": computedString(),
+						// The perspective's ref_id for the static group or
+						// dynamic group value. Empty if the name matches
+						// none, or more than one.
+						"ref_id": computedString(),
+						// The static group or dynamic group it belongs to,
+						// as in the resource's group_ref_ids
+						"group_ref_id": computedString(),
+						"name":         computedString(),
+						// Over the whole range
+						"cost": &schema.Schema{
+							Type:     schema.TypeFloat,
+							Computed: true,
+						},
+						"cost_by_period": &schema.Schema{
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeFloat},
+						},
+					},
+				},
+			},
+			// Cost over the whole range by ref_id, of each group and each
+			// dynamic group value. A dynamic group costs the sum of its values.
+			"cost_by_ref_id": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeFloat},
+			},
+			"total": &schema.Schema{
+				Type:     schema.TypeFloat,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceCHTCostReportRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+
+	perspectiveID := d.Get("perspective_id").(string)
+	interval := d.Get("interval").(string)
+	start := d.Get("start").(string)
+	end := d.Get("end").(string)
+	periods, err := reportPeriods(interval, start, end)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	query := client.ReportQuery{
+		Report:     d.Get("report").(string),
+		Interval:   interval,
+		Dimensions: []string{"time", perspectiveID},
+		Measures:   []string{d.Get("measure").(string)},
+		Filters:    []string{"time:select:" + strings.Join(periods, ",")},
+	}
+	report, err := c.Reports.Get(ctx, query)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to load report %s for perspective %s", query.Report, perspectiveID), err, nil)
+	}
+
+	// The report names groups its own way, so they are matched to the
+	// perspective's constants by name
+	pj, err := c.Perspectives.Get(ctx, perspectiveID)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to load perspective %s", perspectiveID), err, nil)
+	}
+
+	groups, err := costByGroup(report, perspectiveID, query.Measures[0], periods)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	constantByName := constantsByName(pj)
+	costByRefID := make(map[string]interface{})
+	total := 0.0
+	for _, g := range groups {
+		g := g.(map[string]interface{})
+		cost := g["cost"].(float64)
+		total += cost
+		g["ref_id"], g["group_ref_id"] = "", ""
+		constant, ok := constantByName[g["name"].(string)]
+		if !ok {
+			continue
+		}
+		g["ref_id"], g["group_ref_id"] = constant.Ref_id, constant.Ref_id
+		costByRefID[constant.Ref_id] = cost
+		if constant.Blk_id != nil {
+			g["group_ref_id"] = *constant.Blk_id
+			blockCost, _ := costByRefID[*constant.Blk_id].(float64)
+			costByRefID[*constant.Blk_id] = blockCost + cost
+		}
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s/%s/%s", d.Get("client_api_id"), perspectiveID, interval, start, end))
+	for attr, value := range map[string]interface{}{
+		"groups":         groups,
+		"cost_by_ref_id": costByRefID,
+		"total":          total,
+	} {
+		err = d.Set(attr, value)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+// reportPeriods lists the names of the time members from start to end
+func reportPeriods(interval, start, end string) ([]string, error) {
+	layout := periodLayouts[interval]
+	first, err := time.Parse(layout, start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %s is not a %s period, like %s", start, interval, layout)
+	}
+	last, err := time.Parse(layout, end)
+	if err != nil {
+		return nil, fmt.Errorf("end: %s is not a %s period, like %s", end, interval, layout)
+	}
+	if last.Before(first) {
+		return nil, fmt.Errorf("end: %s is before start %s", end, start)
+	}
+
+	var periods []string
+	for t := first; !t.After(last); {
+		periods = append(periods, t.Format(layout))
+		if interval == "monthly" {
+			t = t.AddDate(0, 1, 0)
+		} else {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return periods, nil
+}
+
+// costByGroup adds up the measure of each group of the perspective over the
+// periods. Periods CloudHealth has no value for count as 0.
+func costByGroup(report *client.Report, perspectiveID string, measure string, periods []string) ([]interface{}, error) {
+	timeIdx, perspectiveIdx := -1, -1
+	for idx, dimension := range report.Dimensions {
+		switch dimension.Name {
+		case "time":
+			timeIdx = idx
+		case perspectiveID:
+			perspectiveIdx = idx
+		}
+	}
+	if timeIdx == -1 || perspectiveIdx == -1 || len(report.Dimensions) != 2 {
+		return nil, fmt.Errorf("The report isn't broken down by time and perspective %s", perspectiveID)
+	}
+
+	measureIdx := -1
+	for idx, m := range report.Measures {
+		if m.Name == measure {
+			measureIdx = idx
+		}
+	}
+	if measureIdx == -1 {
+		return nil, fmt.Errorf("There is no measure %s in the report", measure)
+	}
+
+	wanted := make(map[string]bool)
+	for _, period := range periods {
+		wanted[period] = true
+	}
+
+	groups := make([]interface{}, 0)
+	for groupMember, group := range report.Dimensions[perspectiveIdx].Members {
+		if group.IsTotal() {
+			continue
+		}
+		cost := 0.0
+		costByPeriod := make(map[string]interface{})
+		for timeMember, period := range report.Dimensions[timeIdx].Members {
+			if !wanted[period.Name] {
+				continue
+			}
+			members := make([]int, 2)
+			members[timeIdx] = timeMember
+			members[perspectiveIdx] = groupMember
+			value, _ := report.Value(measureIdx, members...)
+			costByPeriod[period.Name] = value
+			cost += value
+		}
+		groups = append(groups, map[string]interface{}{
+			"member_id":      group.Name,
+			"name":           group.Label,
+			"cost":           cost,
+			"cost_by_period": costByPeriod,
+		})
+	}
+	return groups, nil
+}
+
+// constantsByName finds the static groups and dynamic group values of a
+// perspective by name. Names used more than once are left out, as a report
+// can't tell them apart.
+func constantsByName(pj *client.PerspectiveJSON) map[string]client.ConstantItem {
+	result := make(map[string]client.ConstantItem)
+	ambiguous := make(map[string]bool)
+	for _, constant := range pj.Schema.Constants {
+		if constant.Type != client.StaticGroupType && constant.Type != client.DynamicGroupType {
+			continue
+		}
+		for _, item := range constant.List {
+			if _, ok := result[item.Name]; ok {
+				ambiguous[item.Name] = true
+			}
+			result[item.Name] = item
+		}
+	}
+	for name := range ambiguous {
+		delete(result, name)
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// A report on ../test/value_name_perspective.json. The perspective dimension
+// comes first, to check the dimensions are found by name.
+const costReport = `{
+	"dimensions": [
+		{"1234": [
+			{"name": "total", "label": "Total", "parent": -1},
+			{"name": "2199023255553", "label": "Web", "parent": -1},
+			{"name": "2199023255554", "label": "Infra SRE", "parent": -1},
+			{"name": "2199023255555", "label": "Other", "parent": -1},
+			{"name": "2199023255556", "label": "Deleted Group", "parent": -1}
+		]},
+		{"time": [
+			{"name": "total", "label": "Total", "parent": -1},
+			{"name": "2024-01", "label": "Jan 2024", "parent": -1},
+			{"name": "2024-02", "label": "Feb 2024", "parent": -1}
+		]}
+	],
+	"measures": [{"name": "cost", "label": "Cost ($)"}],
+	"data": [
+		[[41.5], [20.25], [21.25]],
+		[[20.5], [10.25], [10.25]],
+		[[10.0], [null], [10.0]],
+		[[10.0], [10.0], [0.0]],
+		[[1.0], [0.0], [1.0]]
+	]
+}`
+
+func TestDataSourceCostReport(t *testing.T) {
+	perspective := perspectiveHandler(t, `{}`, "../test/value_name_perspective.json")
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/olap_reports/cost/history" {
+			perspective(w, r)
+			return
+		}
+		assert.Equal(t, []string{"time", "1234"}, r.URL.Query()["dimensions[]"])
+		assert.Equal(t, []string{"time:select:2024-01,2024-02"}, r.URL.Query()["filters[]"])
+		w.Write([]byte(costReport))
+	})
+
+	dataSource := dataSourceCHTCostReport()
+	// Raw, so that the defaults are applied
+	rd := schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{
+		"perspective_id": "1234",
+		"start":          "2024-01",
+		"end":            "2024-02",
+	})
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, "/1234/monthly/2024-01/2024-02", rd.Id())
+	assertEqual(t, rd, "groups.#", 4)
+	assertEqual(t, rd, "groups.0.member_id", "2199023255553")
+	assertEqual(t, rd, "groups.0.ref_id", "4")
+	assertEqual(t, rd, "groups.0.group_ref_id", "1")
+	assertEqual(t, rd, "groups.0.name", "Web")
+	assertEqual(t, rd, "groups.0.cost", 20.5)
+	assertEqual(t, rd, "groups.0.cost_by_period", map[string]interface{}{"2024-01": 10.25, "2024-02": 10.25})
+	assertEqual(t, rd, "groups.1.cost_by_period", map[string]interface{}{"2024-01": 0.0, "2024-02": 10.0})
+	assertEqual(t, rd, "groups.2.ref_id", "5")
+	assertEqual(t, rd, "groups.2.group_ref_id", "5")
+	// A group the perspective no longer has
+	assertEqual(t, rd, "groups.3.ref_id", "")
+	assertEqual(t, rd, "cost_by_ref_id", map[string]interface{}{"1": 30.5, "3": 10.0, "4": 20.5, "5": 10.0})
+	assertEqual(t, rd, "total", 41.5)
+
+	// Lines up with the ref_ids of the resource
+	resource := importPerspective(t, "../test/value_name_perspective.json")
+	costByRefID := rd.Get("cost_by_ref_id").(map[string]interface{})
+	assert.Equal(t, 30.5, costByRefID[resource.Get("dynamic_group.0.ref_id").(string)])
+	assert.Equal(t, "Other", resource.Get("other_group_name"))
+}
+
+func TestConstantsByNameLeavesOutSharedNames(t *testing.T) {
+	pj := &client.PerspectiveJSON{}
+	blk := "1"
+	pj.Schema.Constants = []client.ConstantJSON{
+		{Type: client.StaticGroupType, List: []client.ConstantItem{{Ref_id: "2", Name: "Shared"}, {Ref_id: "3", Name: "Static"}}},
+		{Type: client.DynamicGroupType, List: []client.ConstantItem{{Ref_id: "4", Blk_id: &blk, Name: "Shared"}}},
+		{Type: client.DynamicGroupBlockType, List: []client.ConstantItem{{Ref_id: "1", Name: "Block"}}},
+	}
+
+	byName := constantsByName(pj)
+	assert.Equal(t, 1, len(byName))
+	assert.Equal(t, "3", byName["Static"].Ref_id)
+}
+
+func TestReportPeriods(t *testing.T) {
+	periods, err := reportPeriods("monthly", "2023-11", "2024-02")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2023-11", "2023-12", "2024-01", "2024-02"}, periods)
+
+	periods, err = reportPeriods("daily", "2024-02-28", "2024-03-01")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2024-02-28", "2024-02-29", "2024-03-01"}, periods)
+
+	_, err = reportPeriods("monthly", "2024-01-01", "2024-02")
+	assert.EqualError(t, err, "start: 2024-01-01 is not a monthly period, like 2006-01")
+
+	_, err = reportPeriods("monthly", "2024-02", "2024-01")
+	assert.EqualError(t, err, "end: 2024-01 is before start 2024-02")
+}
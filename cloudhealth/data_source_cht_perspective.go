@@ -0,0 +1,247 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceCHTPerspective() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTPerspectiveRead,
+
+		Schema: map[string]*schema.Schema{
+			"id": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "name"},
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			// Overrides the provider's client_api_id
+			"client_api_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"include_in_reports": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"other_group_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			// Static groups first, then dynamic groups. Filters on a dynamic
+			// group are its rules of type "filter".
+			"groups": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ref_id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						// "filter" for a static group, "categorize" for a
+						// dynamic group
+						"type": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"rules": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     dataSourceRuleResource(),
+						},
+					},
+				},
+			},
+			"constants": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"constant_type": computedString(),
+						"ref_id":        computedString(),
+						"blk_id":        computedString(),
+						"name":          computedString(),
+						"val":           computedString(),
+						"is_other":      computedString(),
+						"extra_json":    computedString(),
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceRuleResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"type":         computedString(),
+			"asset":        computedString(),
+			"field":        computedStringList(),
+			"tag_field":    computedStringList(),
+			"combine_with": computedString(),
+			"condition": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"field":     computedStringList(),
+						"tag_field": computedStringList(),
+						"op":        computedString(),
+						"val":       computedString(),
+					},
+				},
+			},
+		},
+	}
+}
+
+func computedString() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+}
+
+func computedStringList() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+}
+
+func dataSourceCHTPerspectiveRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+
+	id := d.Get("id").(string)
+	if id == "" {
+		name := d.Get("name").(string)
+		var err error
+		id, err = perspectiveIDByName(ctx, c, name)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to find perspective %s", name), err, nil)
+		}
+	}
+
+	pj, err := c.Perspectives.Get(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to load perspective %s", id), err, nil)
+	}
+	d.SetId(id)
+
+	err = perspectiveToDataSource(pj, d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// perspectiveIDByName finds the ID of the one active perspective with the
+// given name
+func perspectiveIDByName(ctx context.Context, c *client.Client, name string) (string, error) {
+	perspectives, err := c.Perspectives.List(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	for _, perspective := range perspectives {
+		if perspective.Active && perspective.Name == name {
+			ids = append(ids, perspective.ID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("There is no perspective named %s", name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("There are %d perspectives named %s, with IDs %s. Use id instead", len(ids), name, strings.Join(ids, ", "))
+	}
+}
+
+// perspectiveToDataSource loads the perspective into the data source. It is
+// converted as for the resource, with the rules grouped by group.
+func perspectiveToDataSource(pj *client.PerspectiveJSON, d *schema.ResourceData) error {
+	values := newPerspectiveValues()
+	err := perspectiveToTF(pj, values)
+	if err != nil {
+		return err
+	}
+
+	groups := make([]interface{}, 0)
+	for _, block := range []struct{ name, groupType string }{
+		{"static_group", "filter"},
+		{"dynamic_group", "categorize"},
+	} {
+		for _, g := range values[block.name].([]Group) {
+			rules := make([]interface{}, 0)
+			filters, _ := g["filter"].([]map[string]interface{})
+			for _, r := range filters {
+				rules = append(rules, dataSourceRule(r, "filter"))
+			}
+			for _, r := range g["rule"].([]map[string]interface{}) {
+				rules = append(rules, dataSourceRule(r, block.groupType))
+			}
+			groups = append(groups, map[string]interface{}{
+				"name":   g["name"],
+				"ref_id": g["ref_id"],
+				"type":   block.groupType,
+				"rules":  rules,
+			})
+		}
+	}
+
+	for _, attr := range []string{"name", "include_in_reports", "other_group_name"} {
+		err = d.Set(attr, values[attr])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.Set("groups", groups)
+	if err != nil {
+		return err
+	}
+
+	return d.Set("constants", values["constant"])
+}
+
+// dataSourceRule copies a rule as built by perspectiveToTF into the data
+// source's schema
+func dataSourceRule(rule map[string]interface{}, ruleType string) map[string]interface{} {
+	conditions := make([]interface{}, 0)
+	clauses, _ := rule["condition"].([]map[string]interface{})
+	for _, c := range clauses {
+		conditions = append(conditions, map[string]interface{}{
+			"field":     c["field"],
+			"tag_field": c["tag_field"],
+			"op":        c["op"],
+			"val":       c["val"],
+		})
+	}
+	return map[string]interface{}{
+		"type":         ruleType,
+		"asset":        rule["asset"],
+		"field":        rule["field"],
+		"tag_field":    rule["tag_field"],
+		"combine_with": rule["combine_with"],
+		"condition":    conditions,
+	}
+}
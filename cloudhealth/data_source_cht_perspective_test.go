@@ -0,0 +1,98 @@
+package cloudhealth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// perspectiveHandler serves the perspective list, and file for perspective
+// 1234
+func perspectiveHandler(t *testing.T, list string, file string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/perspective_schemas":
+			w.Write([]byte(list))
+		case "/v1/perspective_schemas/1234":
+			data, err := ioutil.ReadFile(file)
+			assert.Nil(t, err)
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Record not found"}`))
+		}
+	}
+}
+
+func TestDataSourcePerspectiveByID(t *testing.T) {
+	meta := newTestMeta(t, perspectiveHandler(t, `{}`, "../test/dynamic_filter_perspective.json"))
+
+	dataSource := dataSourceCHTPerspective()
+	rd := dataSource.TestResourceData()
+	rd.Set("id", "1234")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, "1234", rd.Id())
+	assertEqual(t, rd, "groups.#", 2)
+	assertEqual(t, rd, "groups.0.type", "filter")
+	assertEqual(t, rd, "groups.0.rules.0.type", "filter")
+	assertEqual(t, rd, "groups.1.type", "categorize")
+	assertEqual(t, rd, "groups.1.ref_id", "1")
+	assertEqual(t, rd, "groups.1.rules.0.type", "filter")
+	assertEqual(t, rd, "groups.1.rules.1.type", "categorize")
+	assertEqual(t, rd, "groups.1.rules.1.tag_field", []interface{}{"team"})
+	assert.NotEmpty(t, rd.Get("constants"))
+}
+
+func TestDataSourcePerspectiveByName(t *testing.T) {
+	list := `{
+		"1234": {"name": "My Name", "active": true},
+		"99": {"name": "My Name", "active": false},
+		"5": {"name": "Other Name", "active": true}
+	}`
+	meta := newTestMeta(t, perspectiveHandler(t, list, "../test/static_perspective.json"))
+
+	dataSource := dataSourceCHTPerspective()
+	rd := dataSource.TestResourceData()
+	rd.Set("name", "My Name")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, "1234", rd.Id())
+	assertEqual(t, rd, "include_in_reports", true)
+	assertEqual(t, rd, "other_group_name", "Other")
+	assertEqual(t, rd, "groups.0.name", "Group One")
+	assertEqual(t, rd, "groups.0.rules.0.condition.0.val", "My Account")
+}
+
+func TestDataSourcePerspectiveAmbiguousName(t *testing.T) {
+	list := `{
+		"1234": {"name": "My Name", "active": true},
+		"99": {"name": "My Name", "active": true}
+	}`
+	meta := newTestMeta(t, perspectiveHandler(t, list, "../test/static_perspective.json"))
+
+	dataSource := dataSourceCHTPerspective()
+	rd := dataSource.TestResourceData()
+	rd.Set("name", "My Name")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Detail, "There are 2 perspectives named My Name, with IDs 99, 1234")
+}
+
+func TestDataSourcePerspectiveUnknownName(t *testing.T) {
+	meta := newTestMeta(t, perspectiveHandler(t, `{}`, "../test/static_perspective.json"))
+
+	dataSource := dataSourceCHTPerspective()
+	rd := dataSource.TestResourceData()
+	rd.Set("name", "Missing")
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Detail, "There is no perspective named Missing")
+}
@@ -0,0 +1,98 @@
+package cloudhealth
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceCHTPerspectives() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCHTPerspectivesRead,
+
+		Schema: map[string]*schema.Schema{
+			// Only perspectives whose name matches
+			"name_regex": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			"include_archived": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// Overrides the provider's client_api_id
+			"client_api_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// Ordered by ID
+			"perspectives": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id":   computedString(),
+						"name": computedString(),
+						"archived": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"ids": computedStringList(),
+		},
+	}
+}
+
+func dataSourceCHTPerspectivesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+
+	var nameRegexp *regexp.Regexp
+	if v, ok := d.GetOk("name_regex"); ok {
+		nameRegexp = regexp.MustCompile(v.(string))
+	}
+	includeArchived := d.Get("include_archived").(bool)
+
+	summaries, err := c.Perspectives.List(ctx)
+	if err != nil {
+		return apiErrorDiagnostics("Failed to list perspectives", err, nil)
+	}
+
+	perspectives := make([]interface{}, 0)
+	ids := make([]string, 0)
+	for _, summary := range summaries {
+		if !summary.Active && !includeArchived {
+			continue
+		}
+		if nameRegexp != nil && !nameRegexp.MatchString(summary.Name) {
+			continue
+		}
+		perspectives = append(perspectives, map[string]interface{}{
+			"id":       summary.ID,
+			"name":     summary.Name,
+			"archived": !summary.Active,
+		})
+		ids = append(ids, summary.ID)
+	}
+
+	// The same filters always give the same ID
+	d.SetId(fmt.Sprintf("%s/%s/%s", d.Get("client_api_id"), d.Get("name_regex"), strconv.FormatBool(includeArchived)))
+
+	err = d.Set("perspectives", perspectives)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("ids", ids)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cloudhealth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const perspectiveList = `{
+	"1": {"name": "Teams", "active": true},
+	"2": {"name": "Teams (old)", "active": false},
+	"3": {"name": "Environments", "active": true}
+}`
+
+func TestDataSourcePerspectives(t *testing.T) {
+	meta := newTestMeta(t, perspectiveHandler(t, perspectiveList, ""))
+
+	dataSource := dataSourceCHTPerspectives()
+	rd := dataSource.TestResourceData()
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assertEqual(t, rd, "ids", []interface{}{"1", "3"})
+	assertEqual(t, rd, "perspectives.0.name", "Teams")
+	assertEqual(t, rd, "perspectives.0.archived", false)
+}
+
+func TestDataSourcePerspectivesFilters(t *testing.T) {
+	meta := newTestMeta(t, perspectiveHandler(t, perspectiveList, ""))
+
+	dataSource := dataSourceCHTPerspectives()
+	rd := dataSource.TestResourceData()
+	rd.Set("name_regex", "^Teams")
+	rd.Set("include_archived", true)
+
+	diags := dataSource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assertEqual(t, rd, "ids", []interface{}{"1", "2"})
+	assertEqual(t, rd, "perspectives.1.name", "Teams (old)")
+	assertEqual(t, rd, "perspectives.1.archived", true)
+}
@@ -0,0 +1,210 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Matches a path into the perspective JSON such as
+// "rules[2].condition.clauses[1].op", optionally prefixed by "schema."
+var apiRulePathRegexp = regexp.MustCompile(`(?:schema\.)?rules\[(\d+)\]((?:\.\w+(?:\[\d+\])?)*)`)
+var apiClausePathRegexp = regexp.MustCompile(`^\.condition\.clauses\[(\d+)\](?:\.(\w+))?`)
+var apiListPathRegexp = regexp.MustCompile(`^\.(\w+)(?:\[(\d+)\])?$`)
+
+// apiErrorDiagnostics turns an error from the API client into diagnostics.
+//
+// CloudHealth reports problems against its own JSON, where all rules are in
+// one flat list. rulePaths maps the index of each rule in that list back to
+// the block in the configuration it came from, so that the diagnostic can
+// point at the offending attribute. It may be nil.
+func apiErrorDiagnostics(summary string, err error, rulePaths []cty.Path) diag.Diagnostics {
+	apiErr, ok := err.(*client.APIError)
+	if !ok {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  summary,
+			Detail:   err.Error(),
+		}}
+	}
+
+	if len(apiErr.Details) == 0 {
+		detail := apiErr.Message
+		if detail == "" {
+			detail = apiErr.Body
+		}
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       summary,
+			Detail:        apiStatusDetail(apiErr, detail),
+			AttributePath: apiPathToAttributePath("", detail, rulePaths),
+		}}
+	}
+
+	var diags diag.Diagnostics
+	for _, detail := range apiErr.Details {
+		message := detail.String()
+		if apiErr.Message != "" {
+			message = apiErr.Message + ": " + message
+		}
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Error,
+			Summary:       summary,
+			Detail:        apiStatusDetail(apiErr, message),
+			AttributePath: apiPathToAttributePath(detail.Field, detail.Message, rulePaths),
+		})
+	}
+	return diags
+}
+
+func apiStatusDetail(apiErr *client.APIError, message string) string {
+	return "Cloudhealth returned status code " + strconv.Itoa(apiErr.StatusCode) + ": " + message
+}
+
+// perspectiveRulePaths lists the configuration path of every rule in the order
+// tfToPerspective sends them
+func perspectiveRulePaths(d *schema.ResourceData) []cty.Path {
+	var paths []cty.Path
+	if d.Get("rule_order").(string) == apiRuleOrder {
+		for ruleIdx := range getArray(d, "rule") {
+			paths = append(paths, cty.GetAttrPath("rule").IndexInt(ruleIdx))
+		}
+		return paths
+	}
+	for _, block := range []string{"static_group", "dynamic_group"} {
+		for groupIdx, g := range getArray(d, block) {
+			g := g.(map[string]interface{})
+			groupPath := cty.GetAttrPath(block).IndexInt(groupIdx)
+			filters, _ := g["filter"].([]interface{})
+			for filterIdx := range filters {
+				paths = append(paths, groupPath.Copy().GetAttr("filter").IndexInt(filterIdx))
+			}
+			rules, _ := g["rule"].([]interface{})
+			for ruleIdx := range rules {
+				paths = append(paths, groupPath.Copy().GetAttr("rule").IndexInt(ruleIdx))
+			}
+		}
+	}
+	return paths
+}
+
+// apiPathToAttributePath converts a path into the perspective JSON to the
+// matching path in the configuration. If field is empty the message is
+// searched for a path instead. Returns nil if nothing matches.
+func apiPathToAttributePath(field string, message string, rulePaths []cty.Path) cty.Path {
+	switch field {
+	case "name", "schema.name":
+		return cty.GetAttrPath("name")
+	case "include_in_reports", "schema.include_in_reports":
+		return cty.GetAttrPath("include_in_reports")
+	}
+
+	text := field
+	if text == "" {
+		text = message
+	}
+	match := apiRulePathRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+	ruleIdx, err := strconv.Atoi(match[1])
+	if err != nil || ruleIdx >= len(rulePaths) {
+		return nil
+	}
+	path := rulePaths[ruleIdx].Copy()
+	rest := match[2]
+
+	if clause := apiClausePathRegexp.FindStringSubmatch(rest); clause != nil {
+		clauseIdx, _ := strconv.Atoi(clause[1])
+		path = path.GetAttr("condition").IndexInt(clauseIdx)
+		if clause[2] != "" {
+			path = path.GetAttr(clause[2])
+		}
+		return path
+	}
+	if rest == ".condition.combine_with" {
+		return path.GetAttr("combine_with")
+	}
+	if attr := apiListPathRegexp.FindStringSubmatch(rest); attr != nil {
+		switch attr[1] {
+		case "asset", "field", "tag_field":
+			path = path.GetAttr(attr[1])
+			if attr[2] != "" {
+				idx, _ := strconv.Atoi(attr[2])
+				path = path.IndexInt(idx)
+			}
+		}
+	}
+	return path
+}
+
+// attributePath converts a key as taken by ResourceData and ResourceDiff, such
+// as "group.0.rule.1", to a path for diagnostics
+func attributePath(key string) cty.Path {
+	var path cty.Path
+	for _, step := range strings.Split(key, ".") {
+		if idx, err := strconv.Atoi(step); err == nil && len(path) > 0 {
+			path = path.IndexInt(idx)
+		} else {
+			path = path.GetAttr(step)
+		}
+	}
+	return path
+}
+
+// attributePathKey is the reverse of attributePath
+func attributePathKey(path cty.Path) string {
+	steps := make([]string, 0, len(path))
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			steps = append(steps, step.Name)
+		case cty.IndexStep:
+			if step.Key.Type() == cty.Number {
+				idx, _ := step.Key.AsBigFloat().Int64()
+				steps = append(steps, strconv.FormatInt(idx, 10))
+			} else {
+				steps = append(steps, step.Key.AsString())
+			}
+		}
+	}
+	return strings.Join(steps, ".")
+}
+
+// invalidAttribute reports a problem with the attribute at key, in the form
+// ResourceData and ResourceDiff take
+func invalidAttribute(key string, format string, args ...interface{}) diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity:      diag.Error,
+		Summary:       fmt.Sprintf(format, args...),
+		AttributePath: attributePath(key),
+	}
+}
+
+// diagnosticsError joins diagnostics into one error for the places the SDK
+// only takes an error, such as CustomizeDiff. Each one is prefixed by the
+// attribute at fault, as the error can't carry the paths. Returns nil if there
+// are no errors.
+func diagnosticsError(summary string, diags diag.Diagnostics) error {
+	if !diags.HasError() {
+		return nil
+	}
+	lines := make([]string, 0, len(diags))
+	for _, d := range diags {
+		line := d.Summary
+		if d.Detail != "" {
+			line += ": " + d.Detail
+		}
+		if len(d.AttributePath) > 0 {
+			line = attributePathKey(d.AttributePath) + ": " + line
+		}
+		lines = append(lines, line)
+	}
+	return fmt.Errorf("%s:\n  %s", summary, strings.Join(lines, "\n  "))
+}
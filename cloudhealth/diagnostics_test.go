@@ -0,0 +1,132 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerspectiveRulePaths(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                           "My Name",
+			"static_group.#":                 "1",
+			"static_group.0.name":            "One",
+			"static_group.0.rule.#":          "1",
+			"static_group.0.rule.0.asset":    "AwsAsset",
+			"dynamic_group.#":                "1",
+			"dynamic_group.0.name":           "Two",
+			"dynamic_group.0.filter.#":       "1",
+			"dynamic_group.0.filter.0.asset": "AwsAsset",
+			"dynamic_group.0.rule.#":         "2",
+			"dynamic_group.0.rule.0.asset":   "AwsAsset",
+			"dynamic_group.0.rule.1.asset":   "AwsAccount",
+		},
+	})
+
+	paths := perspectiveRulePaths(rd)
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("static_group").IndexInt(0).GetAttr("rule").IndexInt(0),
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("filter").IndexInt(0),
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("rule").IndexInt(0),
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("rule").IndexInt(1),
+	}, paths)
+}
+
+func TestPerspectiveRulePathsApiRuleOrder(t *testing.T) {
+	rd := resourceCHTPerspective().Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"rule_order":          "api",
+			"static_group.#":      "1",
+			"static_group.0.name": "Group One",
+			"rule.#":              "2",
+			"rule.0.group":        "Group One",
+			"rule.1.group":        "Group One",
+		},
+	})
+
+	paths := perspectiveRulePaths(rd)
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("rule").IndexInt(0),
+		cty.GetAttrPath("rule").IndexInt(1),
+	}, paths)
+}
+
+func TestAPIPathToAttributePath(t *testing.T) {
+	rulePaths := []cty.Path{
+		cty.GetAttrPath("static_group").IndexInt(0).GetAttr("rule").IndexInt(0),
+		cty.GetAttrPath("static_group").IndexInt(2).GetAttr("rule").IndexInt(0),
+	}
+	rule := rulePaths[1]
+
+	cases := []struct {
+		field    string
+		message  string
+		expected cty.Path
+	}{
+		{"rules[1].condition.clauses[1].op", "", rule.GetAttr("condition").IndexInt(1).GetAttr("op")},
+		{"schema.rules[1].condition.clauses[0]", "", rule.GetAttr("condition").IndexInt(0)},
+		{"rules[1].condition.combine_with", "", rule.GetAttr("combine_with")},
+		{"rules[1].asset", "", rule.GetAttr("asset")},
+		{"rules[1].field[0]", "", rule.GetAttr("field").IndexInt(0)},
+		{"rules[1].to", "", rule},
+		{"", "Invalid operator in rules[1].condition.clauses[0].op", rule.GetAttr("condition").IndexInt(0).GetAttr("op")},
+		{"name", "", cty.GetAttrPath("name")},
+		{"rules[5].asset", "", nil},
+		{"constants", "", nil},
+		{"", "Something went wrong", nil},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, apiPathToAttributePath(c.field, c.message, rulePaths), c.field+c.message)
+	}
+}
+
+func TestAPIErrorDiagnostics(t *testing.T) {
+	rulePaths := []cty.Path{cty.GetAttrPath("static_group").IndexInt(0).GetAttr("rule").IndexInt(0)}
+	err := &client.APIError{
+		StatusCode: 422,
+		Message:    "Invalid schema",
+		Details: []client.APIErrorDetail{
+			{Field: "rules[0].condition.clauses[0].op", Message: "unknown operator"},
+			{Message: "something else"},
+		},
+	}
+
+	diags := apiErrorDiagnostics("Failed to update perspective 1", err, rulePaths)
+	assert.Equal(t, 2, len(diags))
+	assert.Equal(t, "Failed to update perspective 1", diags[0].Summary)
+	assert.Equal(t, "Cloudhealth returned status code 422: Invalid schema: rules[0].condition.clauses[0].op: unknown operator", diags[0].Detail)
+	assert.Equal(t, rulePaths[0].GetAttr("condition").IndexInt(0).GetAttr("op"), diags[0].AttributePath)
+	assert.Nil(t, diags[1].AttributePath)
+
+	diags = apiErrorDiagnostics("Failed to delete perspective 1", &client.APIError{StatusCode: 500, Body: "oops"}, nil)
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, "Cloudhealth returned status code 500: oops", diags[0].Detail)
+
+	diags = apiErrorDiagnostics("Failed to load perspective 1", errors.New("connection refused"), nil)
+	assert.Equal(t, "connection refused", diags[0].Detail)
+}
+
+func TestAttributePath(t *testing.T) {
+	path := attributePath("group.0.rule.12.tag_field")
+	assert.Equal(t, cty.GetAttrPath("group").IndexInt(0).GetAttr("rule").IndexInt(12).GetAttr("tag_field"), path)
+	assert.Equal(t, "group.0.rule.12.tag_field", attributePathKey(path))
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	assert.Nil(t, diagnosticsError("Invalid perspective", nil))
+
+	err := diagnosticsError("Invalid perspective", diag.Diagnostics{
+		invalidAttribute("group.1.name", "%s is taken", "Same"),
+		invalidAttribute("rule", "not allowed"),
+	})
+	assert.Equal(t, "Invalid perspective:\n  group.1.name: Same is taken\n  rule: not allowed", err.Error())
+}
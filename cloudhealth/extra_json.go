@@ -0,0 +1,81 @@
+package cloudhealth
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Fields CloudHealth returns that this provider doesn't know about are kept in
+// state as opaque JSON in extra_json attributes, and sent back unchanged.
+// Levels of the API's JSON that have no block of their own are nested under
+// the key of their field: the condition of a rule under "condition", and the
+// constant lists under "constants" by constant type.
+
+// extraToJSON converts unknown fields to the value of an extra_json attribute
+func extraToJSON(extra map[string]json.RawMessage) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(extra)
+	if err != nil {
+		// Can't happen, the fields were decoded from valid JSON
+		log.Printf("[WARN] Failed to keep unknown CloudHealth fields because %s", err)
+		return ""
+	}
+	return string(data)
+}
+
+// jsonToExtra converts the value of an extra_json attribute back to unknown
+// fields
+func jsonToExtra(value interface{}) map[string]json.RawMessage {
+	s := stringOrNil(value)
+	if s == "" {
+		return nil
+	}
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &extra); err != nil {
+		log.Printf("[WARN] Ignoring invalid extra_json %s because %s", s, err)
+		return nil
+	}
+	return extra
+}
+
+// nestExtra adds the unknown fields of a nested level to extra under key
+func nestExtra(extra map[string]json.RawMessage, key string, nested map[string]json.RawMessage) map[string]json.RawMessage {
+	if len(nested) == 0 {
+		return extra
+	}
+	data, err := json.Marshal(nested)
+	if err != nil {
+		return extra
+	}
+	result := make(map[string]json.RawMessage, len(extra)+1)
+	for k, v := range extra {
+		result[k] = v
+	}
+	result[key] = data
+	return result
+}
+
+// unnestExtra splits the unknown fields of the level nested under key from
+// extra
+func unnestExtra(extra map[string]json.RawMessage, key string) (map[string]json.RawMessage, map[string]json.RawMessage) {
+	raw, ok := extra[key]
+	if !ok {
+		return extra, nil
+	}
+	rest := make(map[string]json.RawMessage, len(extra))
+	for k, v := range extra {
+		if k != key {
+			rest[k] = v
+		}
+	}
+	if len(rest) == 0 {
+		rest = nil
+	}
+	var nested map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		log.Printf("[WARN] Ignoring invalid extra_json %s because %s", raw, err)
+	}
+	return rest, nested
+}
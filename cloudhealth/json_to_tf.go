@@ -1,26 +1,57 @@
 package cloudhealth
 
 import (
+	"cloudhealth/client"
+	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/ugorji/go/codec"
 	"strconv"
 )
 
-func jsonToTF(rawData []byte, d *schema.ResourceData) error {
-	// parse the json
-	var pj PerspectiveJSON
+type Group map[string]interface{}
+
+// perspectiveState is what perspectiveToTF reads the previous values of the
+// perspective from and writes the new ones to. *schema.ResourceData is one.
+type perspectiveState interface {
+	Get(key string) interface{}
+	Set(key string, value interface{}) error
+}
+
+// perspectiveValues is a perspectiveState that keeps the values as
+// perspectiveToTF builds them, for data sources that don't have the
+// resource's schema
+type perspectiveValues map[string]interface{}
+
+// newPerspectiveValues starts with the values perspectiveToTF reads as they
+// are for a perspective not in the state, with the rules grouped by group
+func newPerspectiveValues() perspectiveValues {
+	return perspectiveValues{
+		"rule_order":    groupRuleOrder,
+		"group_ref_ids": make(map[string]interface{}),
+		"dynamic_group": make([]interface{}, 0),
+	}
+}
 
-	var jsonHandle codec.JsonHandle
-	jsonHandle.ErrorIfNoField = true
+func (v perspectiveValues) Get(key string) interface{} {
+	return v[key]
+}
+
+func (v perspectiveValues) Set(key string, value interface{}) error {
+	v[key] = value
+	return nil
+}
 
-	var dec *codec.Decoder = codec.NewDecoderBytes(rawData, &jsonHandle)
-	err := dec.Decode(&pj)
+func jsonToTF(rawData []byte, d *schema.ResourceData) error {
+	// parse the json
+	pj, err := client.DecodePerspective(rawData)
 	if err != nil {
 		return fmt.Errorf("Unable to parse json for perspective %s because %s", d.Id(), err)
 	}
+	return perspectiveToTF(pj, d)
+}
 
-	// Now load the json into TF schema
+func perspectiveToTF(pj *client.PerspectiveJSON, d perspectiveState) (err error) {
+	// Load the json into TF schema
 
 	d.Set("name", pj.Schema.Name)
 
@@ -31,28 +62,110 @@ func jsonToTF(rawData []byte, d *schema.ResourceData) error {
 		return err
 	}
 
+	ruleOrder := d.Get("rule_order").(string)
+	if ruleOrder == "" {
+		// Not known yet, e.g. when importing. Only use the API's order if
+		// regrouping the rules would change it
+		ruleOrder = groupRuleOrder
+		if rulesInterleaved(pj) {
+			ruleOrder = apiRuleOrder
+		}
+	}
+	err = d.Set("rule_order", ruleOrder)
+	if err != nil {
+		return err
+	}
+
 	groupByRef := jsonToGroups(pj)
-	groups, err := populateRules(pj, groupByRef)
+	// Keys only exist in the configuration, keep them. Forget the keys of
+	// groups that are gone. Always set, so that an imported perspective
+	// doesn't plan to compute them
+	refIdByKey := make(map[string]interface{})
+	for key, refId := range d.Get("group_ref_ids").(map[string]interface{}) {
+		if group, ok := groupByRef[refId.(string)]; ok {
+			group["key"] = key
+			refIdByKey[key] = refId
+		}
+	}
+	err = d.Set("group_ref_ids", refIdByKey)
+	if err != nil {
+		return err
+	}
+
+	// Display names given to values are kept in the order they already have
+	previousValueNames := valueNamesByGroup(d.Get("dynamic_group").([]interface{}))
+	for refId, group := range groupByRef {
+		if group["type"] == "categorize" {
+			group["value_name"] = buildValueNames(pj, refId, previousValueNames[refId])
+		}
+	}
+	var groups []Group
+	orderedRules := make([]map[string]interface{}, 0)
+	if ruleOrder == apiRuleOrder {
+		groups = orderedGroups(pj, groupByRef)
+		orderedRules, err = buildOrderedRules(pj, groupByRef)
+	} else {
+		groups, err = populateRules(pj, groupByRef)
+	}
 	if err != nil {
 		return err
 	}
 
 	constants := buildConstants(pj)
 
-	d.Set("group", groups)
+	// Without an "Other" group there is nothing to name yet, so keep the
+	// configured name
+	if other := otherGroupConstant(pj); other != nil {
+		err = d.Set("other_group_name", other.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	staticGroups, dynamicGroups := splitGroups(groups)
+	err = d.Set("static_group", staticGroups)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("dynamic_group", dynamicGroups)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("rule", orderedRules)
+	if err != nil {
+		return err
+	}
 
 	err = d.Set("constant", constants)
 	if err != nil {
 		return err
 	}
+
+	err = d.Set("merge", buildMerges(pj))
+	if err != nil {
+		return err
+	}
+
+	constantsExtra := make(map[string]json.RawMessage)
+	for _, constant := range pj.Schema.Constants {
+		if len(constant.Extra) > 0 {
+			constantsExtra[constant.Type] = json.RawMessage(extraToJSON(constant.Extra))
+		}
+	}
+	err = d.Set("extra_json", extraToJSON(nestExtra(pj.Schema.Extra, "constants", constantsExtra)))
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func jsonToGroups(pj PerspectiveJSON) (groupByRef map[string]Group) {
+func jsonToGroups(pj *client.PerspectiveJSON) (groupByRef map[string]Group) {
 	groupByRef = make(map[string]Group)
 
 	for _, constant := range pj.Schema.Constants {
-		if constant.Type != StaticGroupType && constant.Type != DynamicGroupBlockType {
+		if constant.Type != client.StaticGroupType && constant.Type != client.DynamicGroupBlockType {
 			continue
 		}
 		for _, constantGroup := range constant.List {
@@ -64,8 +177,9 @@ func jsonToGroups(pj PerspectiveJSON) (groupByRef map[string]Group) {
 			group["name"] = constantGroup.Name
 			group["ref_id"] = constantGroup.Ref_id
 			group["rule"] = make([]map[string]interface{}, 0)
-			if constant.Type == DynamicGroupBlockType {
+			if constant.Type == client.DynamicGroupBlockType {
 				group["type"] = "categorize"
+				group["filter"] = make([]map[string]interface{}, 0)
 			} else {
 				group["type"] = "filter"
 			}
@@ -75,7 +189,70 @@ func jsonToGroups(pj PerspectiveJSON) (groupByRef map[string]Group) {
 	return groupByRef
 }
 
-func populateRules(pj PerspectiveJSON, groupByRef map[string]Group) (groups []Group, err error) {
+// buildValueNames lists the display names of the values of a dynamic group.
+// Values named in previousValueNames are always listed, in that order, so
+// that a value_name block naming a value after itself doesn't show a diff.
+// Those CloudHealth has no constant for keep their previous name. Other
+// values are listed if their name differs from the value.
+func buildValueNames(pj *client.PerspectiveJSON, groupRefId string, previousValueNames []interface{}) []map[string]interface{} {
+	nameByVal := make(map[string]string)
+	vals := make([]string, 0)
+	for _, constant := range pj.Schema.Constants {
+		if constant.Type != client.DynamicGroupType {
+			continue
+		}
+		for _, constantItem := range constant.List {
+			if constantItem.Blk_id == nil || *constantItem.Blk_id != groupRefId {
+				continue
+			}
+			if _, ok := nameByVal[constantItem.Val]; !ok {
+				vals = append(vals, constantItem.Val)
+			}
+			nameByVal[constantItem.Val] = constantItem.Name
+		}
+	}
+
+	result := make([]map[string]interface{}, 0)
+	listed := make(map[string]bool)
+	for _, v := range previousValueNames {
+		v := v.(map[string]interface{})
+		val := v["val"].(string)
+		if listed[val] {
+			continue
+		}
+		name, ok := nameByVal[val]
+		if !ok {
+			name = v["name"].(string)
+		}
+		result = append(result, map[string]interface{}{"val": val, "name": name})
+		listed[val] = true
+	}
+	for _, val := range vals {
+		if name := nameByVal[val]; !listed[val] && name != val {
+			result = append(result, map[string]interface{}{"val": val, "name": name})
+			listed[val] = true
+		}
+	}
+	return result
+}
+
+// splitGroups separates static and dynamic groups, keeping their order
+func splitGroups(groups []Group) (staticGroups []Group, dynamicGroups []Group) {
+	staticGroups = make([]Group, 0)
+	dynamicGroups = make([]Group, 0)
+	for _, group := range groups {
+		groupType := group["type"]
+		delete(group, "type")
+		if groupType == "categorize" {
+			dynamicGroups = append(dynamicGroups, group)
+		} else {
+			staticGroups = append(staticGroups, group)
+		}
+	}
+	return staticGroups, dynamicGroups
+}
+
+func populateRules(pj *client.PerspectiveJSON, groupByRef map[string]Group) (groups []Group, err error) {
 	groupByRefSeen := make(map[string]bool)
 	groups = make([]Group, 0)
 	for _, jsonRule := range pj.Schema.Rules {
@@ -101,51 +278,154 @@ func populateRules(pj PerspectiveJSON, groupByRef map[string]Group) (groups []Gr
 			groupByRefSeen[groupRef] = true
 		}
 
-		group["rule"] = append(group["rule"].([]map[string]interface{}), rule)
+		if jsonRule.Type == "filter" && group["type"] == "categorize" {
+			// A filter rule on a Dynamic Group Block limits which assets it
+			// categorizes
+			group["filter"] = append(group["filter"].([]map[string]interface{}), rule)
+		} else if jsonRule.Type == group["type"] {
+			group["rule"] = append(group["rule"].([]map[string]interface{}), rule)
+		} else {
+			return nil, fmt.Errorf("Unknown rule type %s; expected %s", jsonRule.Type, group["type"])
+		}
+
+		buildRule(jsonRule, rule)
+	}
 
-		if jsonRule.Type != group["type"] {
+	return groups, nil
+}
+
+// buildRule fills in rule from jsonRule, apart from the group it belongs to
+func buildRule(jsonRule client.RuleJSON, rule map[string]interface{}) {
+	rule["asset"] = jsonRule.Asset
+	if jsonRule.Type == "categorize" {
+		rule["tag_field"] = stringList(jsonRule.Tag_field)
+		rule["field"] = stringList(jsonRule.Field)
+	}
+
+	extra := jsonRule.Extra
+	if jsonRule.Condition != nil {
+		rule["combine_with"] = jsonRule.Condition.Combine_with
+		jsonClauses := jsonRule.Condition.Clauses
+		if jsonClauses != nil {
+			rule["condition"] = buildCondition(jsonClauses)
+		}
+		extra = nestExtra(extra, "condition", jsonRule.Condition.Extra)
+	}
+	rule["extra_json"] = extraToJSON(extra)
+}
+
+// orderedGroups lists the groups in the order of their constants, which is the
+// order tfToJson writes them back in
+func orderedGroups(pj *client.PerspectiveJSON, groupByRef map[string]Group) []Group {
+	groups := make([]Group, 0, len(groupByRef))
+	for _, constant := range pj.Schema.Constants {
+		for _, constantGroup := range constant.List {
+			if group, ok := groupByRef[constantGroup.Ref_id]; ok && (constant.Type == client.StaticGroupType || constant.Type == client.DynamicGroupBlockType) {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
+// buildOrderedRules lists the rules in exactly the order of the API, for
+// rule_order = "api". Each rule names the group it belongs to.
+func buildOrderedRules(pj *client.PerspectiveJSON, groupByRef map[string]Group) ([]map[string]interface{}, error) {
+	rules := make([]map[string]interface{}, 0, len(pj.Schema.Rules))
+	for _, jsonRule := range pj.Schema.Rules {
+		group := groupByRef[ruleGroupRef(jsonRule)]
+		if group == nil {
+			return nil, fmt.Errorf("Group reference %s not found", ruleGroupRef(jsonRule))
+		}
+		if jsonRule.Type != group["type"] && !(jsonRule.Type == "filter" && group["type"] == "categorize") {
 			return nil, fmt.Errorf("Unknown rule type %s; expected %s", jsonRule.Type, group["type"])
 		}
-		rule["asset"] = jsonRule.Asset
-		if jsonRule.Tag_field != nil {
-			rule["tag_field"] = jsonRule.Tag_field
+
+		rule := map[string]interface{}{
+			"group": group["name"],
 		}
-		if jsonRule.Field != nil {
-			rule["field"] = jsonRule.Field
+		if jsonRule.Type != group["type"] {
+			rule["type"] = jsonRule.Type
 		}
+		buildRule(jsonRule, rule)
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
 
-		if jsonRule.Condition != nil {
-			rule["combine_with"] = jsonRule.Condition.Combine_with
-			jsonClauses := jsonRule.Condition.Clauses
-			if jsonClauses != nil {
-				rule["condition"] = buildCondition(jsonClauses)
+// rulesInterleaved returns true if regrouping the rules by group, as
+// rule_order = "group" does, would change their order
+func rulesInterleaved(pj *client.PerspectiveJSON) bool {
+	blocks := make(map[string]bool)
+	for _, constant := range pj.Schema.Constants {
+		if constant.Type == client.DynamicGroupBlockType {
+			for _, constantGroup := range constant.List {
+				blocks[constantGroup.Ref_id] = true
 			}
 		}
 	}
 
-	return groups, nil
+	// Work out the order populateRules and tfToJson would put the rules in:
+	// static groups then dynamic group blocks, each in the order they are
+	// first seen, and filters on dynamic group blocks before the other rules
+	// of the group
+	staticOrder := make([]string, 0)
+	blockOrder := make([]string, 0)
+	filtersByGroup := make(map[string][]int)
+	rulesByGroup := make(map[string][]int)
+	for idx, jsonRule := range pj.Schema.Rules {
+		ref := ruleGroupRef(jsonRule)
+		if _, seen := rulesByGroup[ref]; !seen {
+			if blocks[ref] {
+				blockOrder = append(blockOrder, ref)
+			} else {
+				staticOrder = append(staticOrder, ref)
+			}
+			rulesByGroup[ref] = make([]int, 0)
+		}
+		if jsonRule.Type == "filter" && blocks[ref] {
+			filtersByGroup[ref] = append(filtersByGroup[ref], idx)
+		} else {
+			rulesByGroup[ref] = append(rulesByGroup[ref], idx)
+		}
+	}
+
+	expected := 0
+	for _, ref := range append(staticOrder, blockOrder...) {
+		for _, idx := range append(filtersByGroup[ref], rulesByGroup[ref]...) {
+			if idx != expected {
+				return true
+			}
+			expected++
+		}
+	}
+	return false
+}
+
+func ruleGroupRef(jsonRule client.RuleJSON) string {
+	if jsonRule.To != "" {
+		return jsonRule.To
+	}
+	return jsonRule.Ref_id
 }
 
-func buildCondition(jsonClauses []ClauseJSON) (clauses []map[string]interface{}) {
+func buildCondition(jsonClauses []client.ClauseJSON) (clauses []map[string]interface{}) {
 	clauses = make([]map[string]interface{}, len(jsonClauses))
 
 	for idx, jsonClause := range jsonClauses {
 		clause := make(map[string]interface{})
 		clauses[idx] = clause
 
-		if jsonClause.Tag_field != nil {
-			clause["tag_field"] = jsonClause.Tag_field
-		}
-		if jsonClause.Field != nil {
-			clause["field"] = jsonClause.Field
-		}
+		clause["tag_field"] = stringList(jsonClause.Tag_field)
+		clause["field"] = stringList(jsonClause.Field)
 		clause["op"] = jsonClause.Op
 		clause["val"] = jsonClause.Val
+		clause["extra_json"] = extraToJSON(jsonClause.Extra)
 	}
 	return clauses
 }
 
-func buildConstants(pj PerspectiveJSON) []Group {
+func buildConstants(pj *client.PerspectiveJSON) []Group {
 	result := make([]Group, 0)
 	for _, jsonConstant := range pj.Schema.Constants {
 		for _, jsonConstantGroup := range jsonConstant.List {
@@ -155,6 +435,7 @@ func buildConstants(pj PerspectiveJSON) []Group {
 				"name":          jsonConstantGroup.Name,
 				"val":           jsonConstantGroup.Val,
 				"is_other":      jsonConstantGroup.Is_other,
+				"extra_json":    extraToJSON(jsonConstantGroup.Extra),
 			}
 			if jsonConstantGroup.Blk_id != nil {
 				constant["blk_id"] = *jsonConstantGroup.Blk_id
@@ -164,5 +445,19 @@ func buildConstants(pj PerspectiveJSON) []Group {
 		}
 	}
 
+	sortConstantsByType(result)
+	return result
+}
+
+func buildMerges(pj *client.PerspectiveJSON) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(pj.Schema.Merges))
+	for idx, jsonMerge := range pj.Schema.Merges {
+		result[idx] = map[string]interface{}{
+			"type":       jsonMerge.Type,
+			"to":         jsonMerge.To,
+			"from":       jsonMerge.From,
+			"extra_json": extraToJSON(jsonMerge.Extra),
+		}
+	}
 	return result
 }
@@ -1,6 +1,8 @@
 package cloudhealth
 
 import (
+	"cloudhealth/client"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/yudai/gojsondiff"
@@ -9,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"testing"
 )
@@ -31,44 +34,41 @@ func TestJsonToTFStatic(t *testing.T) {
 
 	assertEqual(t, rd, "name", "My Name")
 	assertEqual(t, rd, "include_in_reports", true)
-	assertEqual(t, rd, "group.#", 3)
-	assertEqual(t, rd, "group.0.name", "Group One")
-	assertEqual(t, rd, "group.0.type", "filter")
-	assertEqual(t, rd, "group.0.ref_id", "1")
-	assertEqual(t, rd, "group.0.rule.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.asset", "AwsAccount")
-	assertEqual(t, rd, "group.0.rule.0.condition.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.condition.0.field.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.condition.0.field.0", "Account Name")
-	assertEqual(t, rd, "group.0.rule.0.condition.0.op", "=")
-	assertEqual(t, rd, "group.0.rule.0.condition.0.val", "My Account")
-
-	assertEqual(t, rd, "group.1.name", "Group Two")
-	assertEqual(t, rd, "group.1.ref_id", "2")
-	assertEqual(t, rd, "group.1.type", "filter")
-	assertEqual(t, rd, "group.1.rule.#", 1)
-	assertEqual(t, rd, "group.1.rule.0.asset", "AwsAccount")
-	assertEqual(t, rd, "group.1.rule.0.combine_with", "OR")
-	assertEqual(t, rd, "group.1.rule.0.condition.#", 2)
-	assertEqual(t, rd, "group.1.rule.0.condition.0.field.#", 1)
-	assertEqual(t, rd, "group.1.rule.0.condition.0.field.0", "Account Name")
-	assertEqual(t, rd, "group.1.rule.0.condition.0.op", "Contains")
-	assertEqual(t, rd, "group.1.rule.0.condition.0.val", "Some Account")
-	assertEqual(t, rd, "group.1.rule.0.condition.1.field.#", 1)
-	assertEqual(t, rd, "group.1.rule.0.condition.1.field.0", "Account Name")
-	assertEqual(t, rd, "group.1.rule.0.condition.1.op", "Contains")
-	assertEqual(t, rd, "group.1.rule.0.condition.1.val", "Another Account")
-
-	assertEqual(t, rd, "group.2.name", "Group Three")
-	assertEqual(t, rd, "group.2.rule.#", 1)
-	assertEqual(t, rd, "group.2.type", "filter")
-	assertEqual(t, rd, "group.2.rule.0.asset", "AwsAsset")
-	assertEqual(t, rd, "group.2.rule.0.condition.#", 1)
-	assertEqual(t, rd, "group.2.rule.0.condition.0.field.#", 0)
-	assertEqual(t, rd, "group.2.rule.0.condition.0.tag_field.#", 1)
-	assertEqual(t, rd, "group.2.rule.0.condition.0.tag_field.0", "team")
-	assertEqual(t, rd, "group.2.rule.0.condition.0.op", "=")
-	assertEqual(t, rd, "group.2.rule.0.condition.0.val", "My Team")
+	assertEqual(t, rd, "static_group.#", 3)
+	assertEqual(t, rd, "static_group.0.name", "Group One")
+	assertEqual(t, rd, "static_group.0.ref_id", "1")
+	assertEqual(t, rd, "static_group.0.rule.#", 1)
+	assertEqual(t, rd, "static_group.0.rule.0.asset", "AwsAccount")
+	assertEqual(t, rd, "static_group.0.rule.0.condition.#", 1)
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.field.#", 1)
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.field.0", "Account Name")
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.op", "=")
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.val", "My Account")
+
+	assertEqual(t, rd, "static_group.1.name", "Group Two")
+	assertEqual(t, rd, "static_group.1.ref_id", "2")
+	assertEqual(t, rd, "static_group.1.rule.#", 1)
+	assertEqual(t, rd, "static_group.1.rule.0.asset", "AwsAccount")
+	assertEqual(t, rd, "static_group.1.rule.0.combine_with", "OR")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.#", 2)
+	assertEqual(t, rd, "static_group.1.rule.0.condition.0.field.#", 1)
+	assertEqual(t, rd, "static_group.1.rule.0.condition.0.field.0", "Account Name")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.0.op", "Contains")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.0.val", "Some Account")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.1.field.#", 1)
+	assertEqual(t, rd, "static_group.1.rule.0.condition.1.field.0", "Account Name")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.1.op", "Contains")
+	assertEqual(t, rd, "static_group.1.rule.0.condition.1.val", "Another Account")
+
+	assertEqual(t, rd, "static_group.2.name", "Group Three")
+	assertEqual(t, rd, "static_group.2.rule.#", 1)
+	assertEqual(t, rd, "static_group.2.rule.0.asset", "AwsAsset")
+	assertEqual(t, rd, "static_group.2.rule.0.condition.#", 1)
+	assertEqual(t, rd, "static_group.2.rule.0.condition.0.field.#", 0)
+	assertEqual(t, rd, "static_group.2.rule.0.condition.0.tag_field.#", 1)
+	assertEqual(t, rd, "static_group.2.rule.0.condition.0.tag_field.0", "team")
+	assertEqual(t, rd, "static_group.2.rule.0.condition.0.op", "=")
+	assertEqual(t, rd, "static_group.2.rule.0.condition.0.val", "My Team")
 
 	assertEqual(t, rd, "constant.#", 4)
 	assertEqual(t, rd, "constant.0.constant_type", "Static Group")
@@ -117,6 +117,255 @@ func TestJsonToTFToJsonStatic(t *testing.T) {
 	assertJsonEqual(t, originalBytes, resultBytes)
 }
 
+func TestJsonToTFInterleaved(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	bytes, err := ioutil.ReadFile("../test/interleaved_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "rule_order", "api")
+	assertEqual(t, rd, "static_group.#", 2)
+	assertEqual(t, rd, "static_group.0.name", "Production")
+	assertEqual(t, rd, "static_group.0.rule.#", 0)
+	assertEqual(t, rd, "static_group.1.name", "Staging")
+	assertEqual(t, rd, "static_group.1.rule.#", 0)
+
+	assertEqual(t, rd, "rule.#", 3)
+	assertEqual(t, rd, "rule.0.group", "Production")
+	assertEqual(t, rd, "rule.0.type", "")
+	assertEqual(t, rd, "rule.0.asset", "AwsAccount")
+	assertEqual(t, rd, "rule.0.condition.0.val", "Production")
+	assertEqual(t, rd, "rule.1.group", "Staging")
+	assertEqual(t, rd, "rule.1.condition.0.op", "Contains")
+	assertEqual(t, rd, "rule.2.group", "Production")
+	assertEqual(t, rd, "rule.2.asset", "AwsAsset")
+	assertEqual(t, rd, "rule.2.condition.0.tag_field.0", "env")
+}
+
+func TestJsonToTFToJsonInterleaved(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	originalBytes, err := ioutil.ReadFile("../test/interleaved_perspective.json")
+	err = jsonToTF(originalBytes, rd)
+	assert.Nil(t, err)
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	// The order of the rules matters, so compare exactly
+	assert.Equal(t, strings.TrimSpace(string(originalBytes)), string(resultBytes))
+}
+
+func TestJsonToTFKeepsGroupRuleOrder(t *testing.T) {
+	resource := resourceCHTPerspective()
+	for _, file := range []string{"static_perspective.json", "dynamic_perspective.json", "dynamic_filter_perspective.json"} {
+		rd := resource.TestResourceData()
+		bytes, err := ioutil.ReadFile("../test/" + file)
+		err = jsonToTF(bytes, rd)
+		assert.Nil(t, err)
+		assertEqual(t, rd, "rule_order", "group")
+		assertEqual(t, rd, "rule.#", 0)
+	}
+}
+
+func TestJsonToTFUsesConfiguredRuleOrder(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.Set("rule_order", "api")
+
+	bytes, err := ioutil.ReadFile("../test/static_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "rule_order", "api")
+	assertEqual(t, rd, "static_group.#", 3)
+	assertEqual(t, rd, "static_group.0.rule.#", 0)
+	assertEqual(t, rd, "rule.#", 3)
+	assertEqual(t, rd, "rule.0.group", "Group One")
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	assertJsonEqual(t, bytes, resultBytes)
+}
+
+func TestJsonToTFUnknownFields(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	originalBytes, err := ioutil.ReadFile("../test/unknown_fields_perspective.json")
+	assert.Nil(t, err)
+	// Rejected by default...
+	assert.NotNil(t, jsonToTF(originalBytes, rd))
+
+	// ...but kept when decoding tolerantly
+	pj, err := client.DecodePerspectiveTolerant(originalBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, perspectiveToTF(pj, rd))
+	assertEqual(t, rd, "extra_json", `{"constants":{"Static Group":{"sorted":true}},"description":{"text":"Added by a newer API"}}`)
+	assertEqual(t, rd, "dynamic_group.0.filter.0.extra_json", `{"condition":{"negate":true}}`)
+	assertEqual(t, rd, "dynamic_group.0.filter.0.condition.0.extra_json", `{"case_sensitive":false}`)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.extra_json", `{"priority":2}`)
+	assertEqual(t, rd, "static_group.0.rule.0.extra_json", `{"priority":3}`)
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.extra_json", "")
+	assertEqual(t, rd, "constant.0.extra_json", `{"color":"#00ff00"}`)
+	assertEqual(t, rd, "merge.0.extra_json", `{"comment":"web is run by infra"}`)
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	assertJsonEqual(t, originalBytes, resultBytes)
+}
+
+func TestJsonToTFKeepsGroupKeys(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.Set("group_ref_ids", map[string]interface{}{"two": "2", "gone": "7"})
+
+	bytes, err := ioutil.ReadFile("../test/static_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "static_group.0.key", "")
+	assertEqual(t, rd, "static_group.1.name", "Group Two")
+	assertEqual(t, rd, "static_group.1.key", "two")
+	// The group with ref_id 7 is gone
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"two": "2"})
+}
+
+func TestJsonToTFValueNames(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	bytes, err := ioutil.ReadFile("../test/value_name_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "dynamic_group.0.value_name.#", 2)
+	assertEqual(t, rd, "dynamic_group.0.value_name.0.val", "team-infra-sre")
+	assertEqual(t, rd, "dynamic_group.0.value_name.0.name", "Infra SRE")
+	assertEqual(t, rd, "dynamic_group.0.value_name.1.val", "team-web")
+	assertEqual(t, rd, "dynamic_group.0.value_name.1.name", "Web")
+}
+
+func TestJsonToTFKeepsValueNameOrder(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.Set("dynamic_group", []interface{}{
+		map[string]interface{}{
+			"name":   "Team",
+			"ref_id": "1",
+			"value_name": []interface{}{
+				map[string]interface{}{"val": "team-web", "name": "Web"},
+				// Named after itself, only listed because it is configured
+				map[string]interface{}{"val": "team-data", "name": "team-data"},
+				// CloudHealth has no constant for this value
+				map[string]interface{}{"val": "team-new", "name": "New"},
+			},
+		},
+	})
+
+	bytes, err := ioutil.ReadFile("../test/value_name_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "dynamic_group.0.value_name", []interface{}{
+		map[string]interface{}{"val": "team-web", "name": "Web"},
+		map[string]interface{}{"val": "team-data", "name": "team-data"},
+		map[string]interface{}{"val": "team-new", "name": "New"},
+		map[string]interface{}{"val": "team-infra-sre", "name": "Infra SRE"},
+	})
+}
+
+func TestJsonToTFToJsonValueNames(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	originalBytes, err := ioutil.ReadFile("../test/value_name_perspective.json")
+	err = jsonToTF(originalBytes, rd)
+	assert.Nil(t, err)
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	assertJsonEqual(t, originalBytes, resultBytes)
+}
+
+func TestJsonToTFOtherGroupName(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	bytes, err := ioutil.ReadFile("../test/static_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "other_group_name", "Other")
+}
+
+func TestJsonToTFMerges(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	bytes, err := ioutil.ReadFile("../test/merge_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+
+	assertEqual(t, rd, "merge.#", 1)
+	assertEqual(t, rd, "merge.0.type", "Dynamic Group")
+	assertEqual(t, rd, "merge.0.to", "2")
+	assertEqual(t, rd, "merge.0.from.#", 2)
+	assertEqual(t, rd, "merge.0.from.0", "3")
+	assertEqual(t, rd, "merge.0.from.1", "4")
+
+	// Perspectives without merges have none
+	bytes, err = ioutil.ReadFile("../test/static_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+	assertEqual(t, rd, "merge.#", 0)
+}
+
+func TestJsonToTFToJsonMerges(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	originalBytes, err := ioutil.ReadFile("../test/merge_perspective.json")
+	err = jsonToTF(originalBytes, rd)
+	assert.Nil(t, err)
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	assertJsonEqual(t, originalBytes, resultBytes)
+}
+
+func TestJsonToTFDynamicWithFilters(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	bytes, err := ioutil.ReadFile("../test/dynamic_filter_perspective.json")
+	err = jsonToTF(bytes, rd)
+	assert.Nil(t, err)
+
+	assertEqual(t, rd, "dynamic_group.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.name", "Team")
+	assertEqual(t, rd, "dynamic_group.0.filter.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.filter.0.asset", "AwsAsset")
+	assertEqual(t, rd, "dynamic_group.0.filter.0.condition.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.filter.0.condition.0.field.0", "Account Name")
+	assertEqual(t, rd, "dynamic_group.0.filter.0.condition.0.val", "Production")
+	assertEqual(t, rd, "dynamic_group.0.rule.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.tag_field.0", "team")
+
+	assertEqual(t, rd, "static_group.#", 1)
+	assertEqual(t, rd, "static_group.0.name", "Shared")
+	assertEqual(t, rd, "static_group.0.rule.#", 1)
+}
+
+func TestJsonToTFToJsonDynamicWithFilters(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+
+	originalBytes, err := ioutil.ReadFile("../test/dynamic_filter_perspective.json")
+	err = jsonToTF(originalBytes, rd)
+	assert.Nil(t, err)
+
+	resultBytes, err := tfToJson(rd)
+	assert.Nil(t, err)
+	assertJsonEqual(t, originalBytes, resultBytes)
+}
+
 func TestJsonToTFDynamic(t *testing.T) {
 	resource := resourceCHTPerspective()
 	rd := resource.TestResourceData()
@@ -127,27 +376,25 @@ func TestJsonToTFDynamic(t *testing.T) {
 
 	assertEqual(t, rd, "name", "My Dynamic")
 	assert.False(t, rd.Get("include_in_reports").(bool), "include_in_reports")
-	assertEqual(t, rd, "group.#", 2)
-	assertEqual(t, rd, "group.0.name", "Group One")
-	assertEqual(t, rd, "group.0.ref_id", "1")
-	assertEqual(t, rd, "group.0.type", "categorize")
-	assertEqual(t, rd, "group.0.rule.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.asset", "AwsAsset")
-	assertEqual(t, rd, "group.0.rule.0.tag_field.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.tag_field.0", "my_tag")
-	assertEqual(t, rd, "group.0.rule.0.condition.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.condition.0.field.#", 1)
-	assertEqual(t, rd, "group.0.rule.0.condition.0.field.0", "Account Name")
-	assertEqual(t, rd, "group.0.rule.0.condition.0.op", "!=")
-	assertEqual(t, rd, "group.0.rule.0.condition.0.val", "Excluded Account")
-	assertEqual(t, rd, "group.1.name", "Group Two")
-	assertEqual(t, rd, "group.1.ref_id", "2")
-	assertEqual(t, rd, "group.1.type", "categorize")
-	assertEqual(t, rd, "group.1.rule.#", 1)
-	assertEqual(t, rd, "group.1.rule.0.asset", "AwsRedshiftCluster")
-	assertEqual(t, rd, "group.1.rule.0.field.#", 1)
-	assertEqual(t, rd, "group.1.rule.0.field.0", "Cluster Identifier")
-	assertEqual(t, rd, "group.1.rule.0.condition.#", 0)
+	assertEqual(t, rd, "dynamic_group.#", 2)
+	assertEqual(t, rd, "dynamic_group.0.name", "Group One")
+	assertEqual(t, rd, "dynamic_group.0.ref_id", "1")
+	assertEqual(t, rd, "dynamic_group.0.rule.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.asset", "AwsAsset")
+	assertEqual(t, rd, "dynamic_group.0.rule.0.tag_field.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.tag_field.0", "my_tag")
+	assertEqual(t, rd, "dynamic_group.0.rule.0.condition.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.condition.0.field.#", 1)
+	assertEqual(t, rd, "dynamic_group.0.rule.0.condition.0.field.0", "Account Name")
+	assertEqual(t, rd, "dynamic_group.0.rule.0.condition.0.op", "!=")
+	assertEqual(t, rd, "dynamic_group.0.rule.0.condition.0.val", "Excluded Account")
+	assertEqual(t, rd, "dynamic_group.1.name", "Group Two")
+	assertEqual(t, rd, "dynamic_group.1.ref_id", "2")
+	assertEqual(t, rd, "dynamic_group.1.rule.#", 1)
+	assertEqual(t, rd, "dynamic_group.1.rule.0.asset", "AwsRedshiftCluster")
+	assertEqual(t, rd, "dynamic_group.1.rule.0.field.#", 1)
+	assertEqual(t, rd, "dynamic_group.1.rule.0.field.0", "Cluster Identifier")
+	assertEqual(t, rd, "dynamic_group.1.rule.0.condition.#", 0)
 	assertEqual(t, rd, "constant.#", 7)
 
 	// These are populated in exactly the order that they're seen in the JSON
@@ -202,12 +449,12 @@ func TestReorderGroup(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Verify ref_ids
-	assertEqual(t, rd, "group.0.ref_id", "1")
-	assertEqual(t, rd, "group.1.ref_id", "2")
-	assertEqual(t, rd, "group.2.ref_id", "3")
+	assertEqual(t, rd, "static_group.0.ref_id", "1")
+	assertEqual(t, rd, "static_group.1.ref_id", "2")
+	assertEqual(t, rd, "static_group.2.ref_id", "3")
 
 	// Simulate re-arranging groups via config - move 2 before 1
-	groups := rd.Get("group").([]interface{})
+	groups := rd.Get("static_group").([]interface{})
 	newGroups := []map[string]interface{}{
 		groups[1].(map[string]interface{}),
 		groups[0].(map[string]interface{}),
@@ -218,7 +465,7 @@ func TestReorderGroup(t *testing.T) {
 	newGroups[0]["ref_id"] = "1"
 	newGroups[1]["ref_id"] = "2"
 	newGroups[2]["ref_id"] = "3"
-	err = rd.Set("group", newGroups)
+	err = rd.Set("static_group", newGroups)
 	assert.Nil(t, err)
 
 	// Convert to json and back to TF
@@ -228,9 +475,9 @@ func TestReorderGroup(t *testing.T) {
 	jsonToTF(b, newRD)
 
 	// The group ref_ids should now be correct!
-	assertEqual(t, newRD, "group.0.ref_id", "2")
-	assertEqual(t, newRD, "group.1.ref_id", "1")
-	assertEqual(t, newRD, "group.2.ref_id", "3")
+	assertEqual(t, newRD, "static_group.0.ref_id", "2")
+	assertEqual(t, newRD, "static_group.1.ref_id", "1")
+	assertEqual(t, newRD, "static_group.2.ref_id", "3")
 }
 
 func TestRenameGroup(t *testing.T) {
@@ -241,9 +488,9 @@ func TestRenameGroup(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Give second group a new name
-	groups := rd.Get("group").([]interface{})
+	groups := rd.Get("static_group").([]interface{})
 	groups[1].(map[string]interface{})["name"] = "My New Name"
-	err = rd.Set("group", groups)
+	err = rd.Set("static_group", groups)
 	assert.Nil(t, err)
 
 	// Convert to json and back to TF
@@ -252,12 +499,12 @@ func TestRenameGroup(t *testing.T) {
 	newRD := resource.TestResourceData()
 	jsonToTF(b, newRD)
 
-	assertEqual(t, newRD, "group.0.ref_id", "1")
-	assertEqual(t, newRD, "group.0.name", "Group One")
-	assertEqual(t, newRD, "group.1.ref_id", "2")
-	assertEqual(t, newRD, "group.1.name", "My New Name")
-	assertEqual(t, newRD, "group.2.ref_id", "3")
-	assertEqual(t, newRD, "group.2.name", "Group Three")
+	assertEqual(t, newRD, "static_group.0.ref_id", "1")
+	assertEqual(t, newRD, "static_group.0.name", "Group One")
+	assertEqual(t, newRD, "static_group.1.ref_id", "2")
+	assertEqual(t, newRD, "static_group.1.name", "My New Name")
+	assertEqual(t, newRD, "static_group.2.ref_id", "3")
+	assertEqual(t, newRD, "static_group.2.name", "Group Three")
 }
 
 func TestRenameAndReorderGroup(t *testing.T) {
@@ -268,9 +515,9 @@ func TestRenameAndReorderGroup(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Give second group a new name
-	groups := rd.Get("group").([]interface{})
+	groups := rd.Get("static_group").([]interface{})
 	groups[1].(map[string]interface{})["name"] = "My New Name"
-	err = rd.Set("group", groups)
+	err = rd.Set("static_group", groups)
 	assert.Nil(t, err)
 
 	// Simulate re-arranging groups via config - move 2 before 1
@@ -285,7 +532,7 @@ func TestRenameAndReorderGroup(t *testing.T) {
 	newGroups[1]["ref_id"] = "2"
 	newGroups[2]["ref_id"] = "3"
 
-	err = rd.Set("group", newGroups)
+	err = rd.Set("static_group", newGroups)
 	assert.Nil(t, err)
 
 	// Convert to json and back to TF
@@ -295,15 +542,15 @@ func TestRenameAndReorderGroup(t *testing.T) {
 	jsonToTF(b, newRD)
 
 	// The first ref_id should be a higher int than the rest of the items in constants
-	refId := rd.Get("group.0.ref_id").(string)
+	refId := rd.Get("static_group.0.ref_id").(string)
 	assert.NotEqual(t, refId, "2")
 	assert.Equal(t, "5", refId)
 
-	assertEqual(t, newRD, "group.0.name", "My New Name")
-	assertEqual(t, newRD, "group.1.ref_id", "1")
-	assertEqual(t, newRD, "group.1.name", "Group One")
-	assertEqual(t, newRD, "group.2.ref_id", "3")
-	assertEqual(t, newRD, "group.2.name", "Group Three")
+	assertEqual(t, newRD, "static_group.0.name", "My New Name")
+	assertEqual(t, newRD, "static_group.1.ref_id", "1")
+	assertEqual(t, newRD, "static_group.1.name", "Group One")
+	assertEqual(t, newRD, "static_group.2.ref_id", "3")
+	assertEqual(t, newRD, "static_group.2.name", "Group Three")
 }
 
 func TestRemoveDynamicGroupBlock(t *testing.T) {
@@ -314,7 +561,7 @@ func TestRemoveDynamicGroupBlock(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Remove Group One
-	groups := rd.Get("group").([]interface{})
+	groups := rd.Get("dynamic_group").([]interface{})
 	newGroups := []map[string]interface{}{
 		groups[1].(map[string]interface{}),
 	}
@@ -322,7 +569,7 @@ func TestRemoveDynamicGroupBlock(t *testing.T) {
 	// Simulate the "breakage" of group.ref_id: they do not follow the list
 	// reordering, so the second-now-first group has ref_id 1
 	newGroups[0]["ref_id"] = "2"
-	err = rd.Set("group", newGroups)
+	err = rd.Set("dynamic_group", newGroups)
 	assert.Nil(t, err)
 
 	// Convert to json and back to TF
@@ -332,7 +579,7 @@ func TestRemoveDynamicGroupBlock(t *testing.T) {
 	jsonToTF(b, newRD)
 
 	// The group ref_ids should now be correct
-	assertEqual(t, newRD, "group.0.ref_id", "2")
+	assertEqual(t, newRD, "dynamic_group.0.ref_id", "2")
 
 	// We should lose two constants: one for the Dynamic Group Block, and one
 	// for the Dynamic Group inside it
@@ -377,3 +624,33 @@ func printJsonDiff(diff gojsondiff.Diff, b []byte) {
 	diffString, _ = formatter2.Format(diff)
 	fmt.Print(diffString)
 }
+
+func TestPerspectiveValuesMatchResourceData(t *testing.T) {
+	resource := resourceCHTPerspective()
+	for _, file := range []string{
+		"../test/static_perspective.json",
+		"../test/dynamic_filter_perspective.json",
+		"../test/value_name_perspective.json",
+		"../test/merge_perspective.json",
+	} {
+		bytes, err := ioutil.ReadFile(file)
+		assert.Nil(t, err)
+		pj, err := client.DecodePerspective(bytes)
+		assert.Nil(t, err)
+
+		rd := resource.TestResourceData()
+		rd.Set("rule_order", groupRuleOrder)
+		assert.Nil(t, perspectiveToTF(pj, rd), file)
+
+		values := newPerspectiveValues()
+		assert.Nil(t, perspectiveToTF(pj, values), file)
+		fromValues := resource.TestResourceData()
+		for key, value := range values {
+			assert.Nil(t, fromValues.Set(key, value), key)
+		}
+
+		for key := range resource.Schema {
+			assert.Equal(t, rd.Get(key), fromValues.Get(key), "%s %s", file, key)
+		}
+	}
+}
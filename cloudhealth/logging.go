@@ -0,0 +1,20 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"log"
+	"sync"
+)
+
+// logRedactor scrubs the API keys of every configured provider from anything
+// written to the standard logger, which Terraform captures under TF_LOG.
+var logRedactor = new(client.Redactor)
+var installLogRedactor sync.Once
+
+func redactLogs(secret string) *client.Redactor {
+	installLogRedactor.Do(func() {
+		log.SetOutput(logRedactor.Writer(log.Writer()))
+	})
+	logRedactor.Add(secret)
+	return logRedactor
+}
@@ -0,0 +1,87 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Values CloudHealth uses when a perspective leaves them out
+const (
+	defaultCombineWith = "OR"
+	defaultOp          = "="
+)
+
+// constantTypes is the order constants are sent in, and kept in state in
+var constantTypes = []string{
+	client.StaticGroupType,
+	client.DynamicGroupType,
+	client.DynamicGroupBlockType,
+}
+
+// suppressDefault treats an empty value and defaultValue as the same, so that
+// a perspective CloudHealth returns with the value left out doesn't plan a
+// change to the default. A new perspective is always sent with the
+// configured value.
+func suppressDefault(defaultValue string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		if d.Id() == "" {
+			return false
+		}
+		if old == "" {
+			old = defaultValue
+		}
+		if new == "" {
+			new = defaultValue
+		}
+		return old == new
+	}
+}
+
+// stringList converts a list from the API for the state, where a missing list
+// is empty
+func stringList(values []string) []string {
+	if values == nil {
+		return make([]string, 0)
+	}
+	return values
+}
+
+// ruleWithDefaults returns a copy of the rule with the values CloudHealth
+// leaves out filled in, so that a rule sent without them compares equal to
+// the one it stores
+func ruleWithDefaults(rule client.RuleJSON) client.RuleJSON {
+	if rule.Condition == nil {
+		return rule
+	}
+	condition := *rule.Condition
+	if condition.Combine_with == "" {
+		condition.Combine_with = defaultCombineWith
+	}
+	condition.Clauses = make([]client.ClauseJSON, len(rule.Condition.Clauses))
+	for idx, clause := range rule.Condition.Clauses {
+		if clause.Op == "" {
+			clause.Op = defaultOp
+		}
+		condition.Clauses[idx] = clause
+	}
+	rule.Condition = &condition
+	return rule
+}
+
+// sortConstantsByType orders constants as tfToJson sends them, keeping the
+// order within each type. Types the provider doesn't know go last.
+func sortConstantsByType(constants []Group) {
+	typeOrder := func(constantType interface{}) int {
+		for idx, t := range constantTypes {
+			if t == constantType {
+				return idx
+			}
+		}
+		return len(constantTypes)
+	}
+	sort.SliceStable(constants, func(i, j int) bool {
+		return typeOrder(constants[i]["constant_type"]) < typeOrder(constants[j]["constant_type"])
+	})
+}
@@ -0,0 +1,116 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// configFromState writes the configuration someone would write for an
+// imported perspective: computed attributes, empty values and defaults are
+// left out
+func configFromState(schemaMap map[string]*schema.Schema, values map[string]interface{}) map[string]interface{} {
+	config := make(map[string]interface{})
+	for k, s := range schemaMap {
+		if s.Computed {
+			continue
+		}
+		switch v := values[k].(type) {
+		case string:
+			if v == "" || v == s.Default || (k == "combine_with" && v == defaultCombineWith) {
+				continue
+			}
+			config[k] = v
+		case bool:
+			if s.Required || v {
+				config[k] = v
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				continue
+			}
+			elem, ok := s.Elem.(*schema.Resource)
+			if !ok {
+				config[k] = v
+				continue
+			}
+			blocks := make([]interface{}, len(v))
+			for idx, block := range v {
+				blocks[idx] = configFromState(elem.Schema, block.(map[string]interface{}))
+			}
+			config[k] = blocks
+		}
+	}
+	return config
+}
+
+// importPerspective loads a perspective into new state as terraform import
+// does
+func importPerspective(t *testing.T, file string) *schema.ResourceData {
+	rawData, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	pj, err := client.DecodePerspectiveTolerant(rawData)
+	assert.Nil(t, err)
+
+	rd := resourceCHTPerspective().TestResourceData()
+	rd.SetId("1234")
+	assert.Nil(t, perspectiveToTF(pj, rd))
+	return rd
+}
+
+func TestImportedPerspectivesPlanNoChanges(t *testing.T) {
+	files, err := filepath.Glob("../test/*.json")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+
+	resource := resourceCHTPerspective()
+	for _, file := range files {
+		rd := importPerspective(t, file)
+		values := make(map[string]interface{})
+		for k := range resource.Schema {
+			values[k] = rd.Get(k)
+		}
+		config := terraform.NewResourceConfigRaw(configFromState(resource.Schema, values))
+
+		diff, err := resource.Diff(context.Background(), rd.State(), config, nil)
+		assert.Nil(t, err, file)
+		if diff != nil && !diff.Empty() {
+			for k, attr := range diff.Attributes {
+				t.Errorf("%s: %s changes from %q to %q", file, k, attr.Old, attr.New)
+			}
+		}
+	}
+}
+
+func TestImportedConstantsInTypeOrder(t *testing.T) {
+	rd := importPerspective(t, "../test/defaults_perspective.json")
+	types := make([]string, 0)
+	for _, c := range rd.Get("constant").([]interface{}) {
+		types = append(types, c.(map[string]interface{})["constant_type"].(string))
+	}
+	assert.Equal(t, []string{
+		client.StaticGroupType,
+		client.StaticGroupType,
+		client.DynamicGroupType,
+		client.DynamicGroupBlockType,
+	}, types)
+}
+
+func TestSuppressDefault(t *testing.T) {
+	suppress := suppressDefault(defaultOp)
+	existing := resourceCHTPerspective().TestResourceData()
+	existing.SetId("1234")
+	assert.True(t, suppress("op", "", "=", existing))
+	assert.True(t, suppress("op", "=", "", existing))
+	assert.False(t, suppress("op", "", "Contains", existing))
+
+	// A new perspective is sent with the configured value
+	created := resourceCHTPerspective().TestResourceData()
+	assert.False(t, suppress("op", "", "=", created))
+}
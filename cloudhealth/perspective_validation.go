@@ -0,0 +1,231 @@
+package cloudhealth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Condition operators accepted by CloudHealth
+var conditionOps = []string{
+	"=",
+	"!=",
+	">",
+	"<",
+	">=",
+	"<=",
+	"Contains",
+	"Does Not Contain",
+	"Starts With",
+	"Does Not Start With",
+	"Ends With",
+	"Does Not End With",
+}
+
+var groupTypes = []string{"filter", "categorize"}
+
+var combineWithValues = []string{"AND", "OR"}
+
+// stringInSlice is validation.StringInSlice with its own copy of the attribute
+// path. The SDK reuses the path's backing array while validating nested
+// blocks, so diagnostics deep in a block would otherwise end up pointing at
+// another attribute.
+func stringInSlice(valid []string) schema.SchemaValidateDiagFunc {
+	validate := validation.ToDiagFunc(validation.StringInSlice(valid, false))
+	return func(v interface{}, path cty.Path) diag.Diagnostics {
+		diags := validate(v, path)
+		for i := range diags {
+			diags[i].AttributePath = path.Copy()
+		}
+		return diags
+	}
+}
+
+// resourceCHTPerspectiveValidateDiff catches at plan time the mistakes that
+// tfToJson, fixRefIDs or the API would otherwise only report on apply. Single
+// attributes are checked by their ValidateFunc; this checks how they fit
+// together.
+func resourceCHTPerspectiveValidateDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	diags, err := perspectivePlanDiagnostics(ctx, d, meta)
+	if err != nil {
+		return err
+	}
+	return diagnosticsError("Invalid perspective", diags)
+}
+
+// perspectivePlanDiagnostics is perspectiveDiagnostics, plus the checks
+// against the asset catalog when the provider has validate_assets set
+func perspectivePlanDiagnostics(ctx context.Context, d *schema.ResourceDiff, meta interface{}) (diag.Diagnostics, error) {
+	diags := perspectiveDiagnostics(d)
+	if m, ok := meta.(*ChtMeta); ok && m.validateAssets {
+		assetTypes, err := m.assetTypes(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load the asset types to validate the perspective because %s", err)
+		}
+		diags = append(diags, assetDiagnostics(d, assetTypes)...)
+	}
+	return diags, nil
+}
+
+// perspectiveDiagnostics lists what is wrong with the planned perspective, each
+// pointing at the attribute at fault. Values that aren't known yet are skipped.
+func perspectiveDiagnostics(d *schema.ResourceDiff) diag.Diagnostics {
+	var diags diag.Diagnostics
+	apiOrder := d.Get("rule_order").(string) == apiRuleOrder
+
+	groupPathByName := make(map[string]string)
+	groupPathByKey := make(map[string]string)
+	groupTypeByName := make(map[string]string)
+	for _, block := range []string{"static_group", "dynamic_group"} {
+		groupType := "filter"
+		if block == "dynamic_group" {
+			groupType = "categorize"
+		}
+		for groupIdx, g := range d.Get(block).([]interface{}) {
+			g := g.(map[string]interface{})
+			groupPath := fmt.Sprintf("%s.%d", block, groupIdx)
+			name := g["name"].(string)
+
+			if name != "" && d.NewValueKnown(groupPath+".name") {
+				if other, ok := groupPathByName[name]; ok {
+					diags = append(diags, invalidAttribute(groupPath+".name", "%s is already the name of %s. Group names must be unique", name, other))
+				} else {
+					groupPathByName[name] = groupPath
+					groupTypeByName[name] = groupType
+				}
+			}
+
+			key := g["key"].(string)
+			if key != "" && d.NewValueKnown(groupPath+".key") {
+				if other, ok := groupPathByKey[key]; ok {
+					diags = append(diags, invalidAttribute(groupPath+".key", "%s is already the key of %s. Group keys must be unique", key, other))
+				} else {
+					groupPathByKey[key] = groupPath
+				}
+			}
+
+			filters, _ := g["filter"].([]interface{})
+			rules, _ := g["rule"].([]interface{})
+			if apiOrder && len(filters)+len(rules) > 0 {
+				diags = append(diags, invalidAttribute(groupPath, "with rule_order = \"api\" all rules must be top level rule blocks"))
+			}
+			if groupType == "categorize" {
+				for ruleIdx, r := range rules {
+					diags = append(diags, categorizeRuleDiagnostics(d, fmt.Sprintf("%s.rule.%d", groupPath, ruleIdx), r.(map[string]interface{}))...)
+				}
+				diags = append(diags, valueNameDiagnostics(d, groupPath, g)...)
+			}
+		}
+	}
+
+	// Only checked when it changes, as CloudHealth names it when not configured
+	otherName := d.Get("other_group_name").(string)
+	if otherName != "" && d.HasChange("other_group_name") && d.NewValueKnown("other_group_name") {
+		if other, ok := groupPathByName[otherName]; ok {
+			diags = append(diags, invalidAttribute("other_group_name", "%s is already the name of %s. Group names must be unique", otherName, other))
+		}
+	}
+
+	rules := d.Get("rule").([]interface{})
+	if !apiOrder && len(rules) > 0 {
+		diags = append(diags, invalidAttribute("rule", "top level rule blocks are only allowed with rule_order = \"api\""))
+	}
+	for ruleIdx, r := range rules {
+		r := r.(map[string]interface{})
+		rulePath := fmt.Sprintf("rule.%d", ruleIdx)
+		groupName := r["group"].(string)
+		if groupName == "" || !d.NewValueKnown(rulePath+".group") {
+			continue
+		}
+		groupType, ok := groupTypeByName[groupName]
+		if !ok {
+			diags = append(diags, invalidAttribute(rulePath+".group", "there is no group named %s", groupName))
+			continue
+		}
+
+		ruleType := r["type"].(string)
+		if ruleType == "" {
+			ruleType = groupType
+		}
+		if ruleType == "categorize" && groupType != "categorize" {
+			diags = append(diags, invalidAttribute(rulePath+".type", "a categorize rule can't be used with filter group %s", groupName))
+		} else if ruleType == "categorize" {
+			diags = append(diags, categorizeRuleDiagnostics(d, rulePath, r)...)
+		}
+	}
+	return diags
+}
+
+// categorizeRuleDiagnostics checks that a categorize rule says what to
+// categorize by
+func categorizeRuleDiagnostics(d *schema.ResourceDiff, rulePath string, rule map[string]interface{}) diag.Diagnostics {
+	if !d.NewValueKnown(rulePath+".field") || !d.NewValueKnown(rulePath+".tag_field") {
+		return nil
+	}
+	fields, _ := rule["field"].([]interface{})
+	tagFields, _ := rule["tag_field"].([]interface{})
+	if len(fields) == 0 && len(tagFields) == 0 {
+		return diag.Diagnostics{invalidAttribute(rulePath, "categorize rules need a field or tag_field")}
+	}
+	return nil
+}
+
+// valueNameDiagnostics checks that a dynamic group names each value only once
+func valueNameDiagnostics(d *schema.ResourceDiff, groupPath string, group map[string]interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	valueNamePathByVal := make(map[string]string)
+	valueNames, _ := group["value_name"].([]interface{})
+	for valueNameIdx, v := range valueNames {
+		valueNamePath := fmt.Sprintf("%s.value_name.%d", groupPath, valueNameIdx)
+		val := v.(map[string]interface{})["val"].(string)
+		if !d.NewValueKnown(valueNamePath + ".val") {
+			continue
+		}
+		if other, ok := valueNamePathByVal[val]; ok {
+			diags = append(diags, invalidAttribute(valueNamePath+".val", "%s is already named by %s. Each value can only have one name", val, other))
+		} else {
+			valueNamePathByVal[val] = valueNamePath
+		}
+	}
+	return diags
+}
+
+// assetDiagnostics checks that every rule uses an asset type from the catalog.
+// Fields aren't checked, as perspectives refer to them by their display names.
+func assetDiagnostics(d *schema.ResourceDiff, assetTypes []string) diag.Diagnostics {
+	known := make(map[string]bool)
+	for _, assetType := range assetTypes {
+		known[assetType] = true
+	}
+
+	var rulePaths []string
+	for _, block := range []string{"static_group", "dynamic_group"} {
+		for groupIdx, g := range d.Get(block).([]interface{}) {
+			g := g.(map[string]interface{})
+			for _, ruleBlock := range []string{"filter", "rule"} {
+				rules, _ := g[ruleBlock].([]interface{})
+				for ruleIdx := range rules {
+					rulePaths = append(rulePaths, fmt.Sprintf("%s.%d.%s.%d", block, groupIdx, ruleBlock, ruleIdx))
+				}
+			}
+		}
+	}
+	for ruleIdx := range d.Get("rule").([]interface{}) {
+		rulePaths = append(rulePaths, fmt.Sprintf("rule.%d", ruleIdx))
+	}
+
+	var diags diag.Diagnostics
+	for _, rulePath := range rulePaths {
+		assetPath := rulePath + ".asset"
+		asset := d.Get(assetPath).(string)
+		if asset == "" || !d.NewValueKnown(assetPath) || known[asset] {
+			continue
+		}
+		diags = append(diags, invalidAttribute(assetPath, "%s is not a CloudHealth asset type", asset))
+	}
+	return diags
+}
@@ -0,0 +1,280 @@
+package cloudhealth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func planPerspective(t *testing.T, config map[string]interface{}) error {
+	return planPerspectiveWithMeta(t, config, nil)
+}
+
+func planPerspectiveWithMeta(t *testing.T, config map[string]interface{}, meta interface{}) error {
+	config["name"] = "My Name"
+	config["include_in_reports"] = true
+	_, err := resourceCHTPerspective().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	return err
+}
+
+// planDiagnostics plans the perspective and returns what
+// perspectivePlanDiagnostics finds wrong with it
+func planDiagnostics(t *testing.T, config map[string]interface{}) diag.Diagnostics {
+	return planDiagnosticsWithMeta(t, config, nil)
+}
+
+func planDiagnosticsWithMeta(t *testing.T, config map[string]interface{}, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	resource := resourceCHTPerspective()
+	resource.CustomizeDiff = func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		var err error
+		diags, err = perspectivePlanDiagnostics(ctx, d, meta)
+		return err
+	}
+	config["name"] = "My Name"
+	config["include_in_reports"] = true
+	_, err := resource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	assert.Nil(t, err)
+	return diags
+}
+
+func diagnosticPaths(diags diag.Diagnostics) []cty.Path {
+	paths := make([]cty.Path, 0)
+	for _, d := range diags {
+		paths = append(paths, d.AttributePath)
+	}
+	return paths
+}
+
+func rule(asset string, extra map[string]interface{}) map[string]interface{} {
+	r := map[string]interface{}{
+		"asset": asset,
+		"condition": []interface{}{
+			map[string]interface{}{"field": []interface{}{"Account Name"}, "val": "x"},
+		},
+	}
+	for k, v := range extra {
+		r[k] = v
+	}
+	return r
+}
+
+func TestValidPerspectivePlans(t *testing.T) {
+	err := planPerspective(t, map[string]interface{}{
+		"static_group": []interface{}{
+			map[string]interface{}{
+				"name": "Static",
+				"rule": []interface{}{rule("AwsAccount", nil)},
+			},
+		},
+		"dynamic_group": []interface{}{
+			map[string]interface{}{
+				"name":   "Teams",
+				"filter": []interface{}{rule("AwsAsset", nil)},
+				"rule":   []interface{}{rule("AwsAsset", map[string]interface{}{"tag_field": []interface{}{"team"}})},
+			},
+		},
+	})
+	assert.Nil(t, err)
+}
+
+func TestInvalidPerspectiveFailsPlan(t *testing.T) {
+	err := planPerspective(t, map[string]interface{}{
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "Same"},
+			map[string]interface{}{"name": "Same"},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "static_group.1.name: Same is already the name of static_group.0")
+}
+
+func TestDuplicateGroupNamesFailPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "Same"},
+			map[string]interface{}{"name": "Other"},
+		},
+		"dynamic_group": []interface{}{
+			map[string]interface{}{"name": "Same"},
+		},
+	})
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("name"),
+	}, diagnosticPaths(diags))
+}
+
+func TestDuplicateGroupKeysFailPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "One", "key": "same"},
+			map[string]interface{}{"name": "Two", "key": "same"},
+		},
+	})
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("static_group").IndexInt(1).GetAttr("key"),
+	}, diagnosticPaths(diags))
+}
+
+func TestCategorizeRuleWithoutFieldFailsPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"dynamic_group": []interface{}{
+			map[string]interface{}{
+				"name": "Teams",
+				"rule": []interface{}{
+					rule("AwsAsset", map[string]interface{}{"field": []interface{}{"Team"}}),
+					rule("AwsAsset", nil),
+				},
+			},
+		},
+	})
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("rule").IndexInt(1),
+	}, diagnosticPaths(diags))
+}
+
+func TestFilterOnStaticGroupFailsValidation(t *testing.T) {
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+		"static_group": []interface{}{
+			map[string]interface{}{
+				"name":   "Static",
+				"filter": []interface{}{rule("AwsAccount", nil)},
+			},
+		},
+	})
+
+	diags := resourceCHTPerspective().Validate(config)
+	assert.True(t, diags.HasError())
+}
+
+func TestApiRuleOrderProblemsFailPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"rule_order": "api",
+		"static_group": []interface{}{
+			map[string]interface{}{
+				"name": "Static",
+				"rule": []interface{}{rule("AwsAccount", nil)},
+			},
+		},
+		"dynamic_group": []interface{}{
+			map[string]interface{}{"name": "Teams"},
+		},
+		"rule": []interface{}{
+			rule("AwsAccount", map[string]interface{}{"group": "Missing"}),
+			rule("AwsAccount", map[string]interface{}{"group": "Static", "type": "categorize"}),
+			rule("AwsAsset", map[string]interface{}{"group": "Teams"}),
+			rule("AwsAsset", map[string]interface{}{"group": "Teams", "type": "filter"}),
+		},
+	})
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("static_group").IndexInt(0),
+		cty.GetAttrPath("rule").IndexInt(0).GetAttr("group"),
+		cty.GetAttrPath("rule").IndexInt(1).GetAttr("type"),
+		cty.GetAttrPath("rule").IndexInt(2),
+	}, diagnosticPaths(diags))
+
+	diags = planDiagnostics(t, map[string]interface{}{
+		"static_group": []interface{}{map[string]interface{}{"name": "Static"}},
+		"rule":         []interface{}{rule("AwsAccount", map[string]interface{}{"group": "Static"})},
+	})
+	assert.Equal(t, []cty.Path{cty.GetAttrPath("rule")}, diagnosticPaths(diags))
+}
+
+func TestInvalidValuesFailValidation(t *testing.T) {
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+		"static_group": []interface{}{
+			map[string]interface{}{
+				"name": "Static",
+				"rule": []interface{}{
+					map[string]interface{}{
+						"asset":        "AwsAccount",
+						"combine_with": "XOR",
+						"condition": []interface{}{
+							map[string]interface{}{"field": []interface{}{"Account Name"}, "op": "==", "val": "x"},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	paths := diagnosticPaths(resourceCHTPerspective().Validate(config))
+	group := cty.GetAttrPath("static_group").IndexInt(0)
+	assert.Contains(t, paths, group.Copy().GetAttr("rule").IndexInt(0).GetAttr("combine_with"))
+	assert.Contains(t, paths, group.Copy().GetAttr("rule").IndexInt(0).GetAttr("condition").IndexInt(0).GetAttr("op"))
+}
+
+func TestDuplicateValueNamesFailPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"dynamic_group": []interface{}{
+			map[string]interface{}{
+				"name": "Teams",
+				"rule": []interface{}{rule("AwsAsset", map[string]interface{}{"tag_field": []interface{}{"team"}})},
+				"value_name": []interface{}{
+					map[string]interface{}{"val": "team-web", "name": "Web"},
+					map[string]interface{}{"val": "team-web", "name": "Website"},
+				},
+			},
+		},
+	})
+	assert.Equal(t, []cty.Path{
+		cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("value_name").IndexInt(1).GetAttr("val"),
+	}, diagnosticPaths(diags))
+}
+
+func TestOtherGroupNameOfGroupFailsPlan(t *testing.T) {
+	diags := planDiagnostics(t, map[string]interface{}{
+		"other_group_name": "Static",
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "Static"},
+		},
+	})
+	assert.Equal(t, []cty.Path{cty.GetAttrPath("other_group_name")}, diagnosticPaths(diags))
+}
+
+func TestUnknownAssetFailsPlanWhenValidatingAssets(t *testing.T) {
+	requests := make(map[string]int)
+	meta := newTestMeta(t, assetHandler(requests))
+	meta.validateAssets = true
+
+	config := map[string]interface{}{
+		"static_group": []interface{}{
+			map[string]interface{}{
+				"name": "Static",
+				"rule": []interface{}{rule("AwsAccount", nil)},
+			},
+		},
+		"dynamic_group": []interface{}{
+			map[string]interface{}{
+				"name":   "Teams",
+				"filter": []interface{}{rule("AwsAsset", nil)},
+				"rule":   []interface{}{rule("AwsInstance", map[string]interface{}{"tag_field": []interface{}{"team"}})},
+			},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		diags := planDiagnosticsWithMeta(t, config, meta)
+		assert.Equal(t, []cty.Path{
+			cty.GetAttrPath("dynamic_group").IndexInt(0).GetAttr("filter").IndexInt(0).GetAttr("asset"),
+		}, diagnosticPaths(diags))
+	}
+	assert.Equal(t, 1, requests["/api.json"], "the catalog is cached")
+
+	// Fails the plan
+	err := planPerspectiveWithMeta(t, config, meta)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dynamic_group.0.filter.0.asset: AwsAsset is not a CloudHealth asset type")
+
+	// Off by default
+	meta.validateAssets = false
+	assert.Nil(t, planPerspectiveWithMeta(t, config, meta))
+}
@@ -1,14 +1,22 @@
 package cloudhealth
 
 import (
+	"cloudhealth/client"
 	"context"
 	"errors"
+	"time"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 type ChtMeta struct {
-	apiKey string
+	client *client.Client
+	// validateAssets checks the assets of perspective rules at plan time
+	validateAssets bool
+	// assets caches the asset catalog for the provider's tenant
+	assets assetCatalog
 }
 
 func Provider() *schema.Provider {
@@ -20,12 +28,105 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("CHT_API_KEY", nil),
 				Description: "API key for Cloudhealth",
 			},
+			"client_api_id": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_CLIENT_API_ID", ""),
+				Description: "For Cloudhealth partners: the customer tenant to manage",
+			},
+			"endpoint": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_ENDPOINT", client.DefaultEndpoint),
+				Description: "Base URL of the Cloudhealth API",
+			},
+			"request_timeout": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_REQUEST_TIMEOUT", 60),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Timeout in seconds for each request to the Cloudhealth API. 0 means no timeout",
+			},
+			"max_retries": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_MAX_RETRIES", 5),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "How many times to retry a request that was throttled or failed with a server error",
+			},
+			"retry_max_wait": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_RETRY_MAX_WAIT", 30),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of seconds to wait between retries",
+			},
+			"requests_per_second": &schema.Schema{
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_REQUESTS_PER_SECOND", 10),
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "Maximum rate of requests to the Cloudhealth API. 0 means unlimited",
+			},
+			"max_concurrent_requests": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_MAX_CONCURRENT_REQUESTS", 10),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of requests to the Cloudhealth API in flight at once. 0 means unlimited",
+			},
+			"debug_dump_dir": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_DEBUG_DUMP_DIR", ""),
+				Description: "Directory to write every API request and response to, as timestamped JSON files",
+			},
+			"proxy_url": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CHT_PROXY_URL", ""),
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsURLWithScheme([]string{"http", "https", "socks5"})),
+				Description:  "Proxy to send requests through. Defaults to the standard HTTP(S)_PROXY environment variables",
+			},
+			"ca_bundle_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_CA_BUNDLE_FILE", ""),
+				Description: "PEM file of extra CA certificates to trust",
+			},
+			"insecure_skip_verify": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_INSECURE_SKIP_VERIFY", false),
+				Description: "Skip TLS certificate verification. Only for testing",
+			},
+			"strict_decoding": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_STRICT_DECODING", false),
+				Description: "Fail on fields in API responses the provider doesn't know about, instead of keeping them in extra_json",
+			},
+			"validate_assets": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CHT_VALIDATE_ASSETS", false),
+				Description: "Check at plan time that perspective rules use asset types CloudHealth knows about. Loads the asset catalog from the API",
+			},
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
 			"cloudhealth_perspective": resourceCHTPerspective(),
 		},
 
+		DataSourcesMap: map[string]*schema.Resource{
+			"cloudhealth_perspective":  dataSourceCHTPerspective(),
+			"cloudhealth_perspectives": dataSourceCHTPerspectives(),
+			"cloudhealth_asset_types":  dataSourceCHTAssetTypes(),
+			"cloudhealth_asset_fields": dataSourceCHTAssetFields(),
+			"cloudhealth_assets":       dataSourceCHTAssets(),
+			"cloudhealth_cost_report":  dataSourceCHTCostReport(),
+		},
+
 		ConfigureContextFunc: providerConfigure,
 	}
 }
@@ -37,8 +138,27 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 	} else {
 		return nil, diag.FromErr(errors.New("Must set CHT_API_KEY or provide a 'key' to the provider"))
 	}
+	c, err := client.New(client.Config{
+		APIKey:             key,
+		Redactor:           redactLogs(key),
+		Endpoint:           d.Get("endpoint").(string),
+		ClientAPIID:        d.Get("client_api_id").(string),
+		Timeout:            time.Duration(d.Get("request_timeout").(int)) * time.Second,
+		MaxRetries:         d.Get("max_retries").(int),
+		RetryMaxWait:       time.Duration(d.Get("retry_max_wait").(int)) * time.Second,
+		Limiter:            client.NewLimiter(d.Get("requests_per_second").(float64), d.Get("max_concurrent_requests").(int)),
+		DumpDir:            d.Get("debug_dump_dir").(string),
+		ProxyURL:           d.Get("proxy_url").(string),
+		CABundleFile:       d.Get("ca_bundle_file").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+		KeepUnknownFields:  !d.Get("strict_decoding").(bool),
+	})
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
 	meta := ChtMeta{
-		apiKey: key,
+		client:         c,
+		validateAssets: d.Get("validate_assets").(bool),
 	}
 	return &meta, nil
 }
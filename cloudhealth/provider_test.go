@@ -1,8 +1,11 @@
 package cloudhealth
 
 import (
+	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -23,15 +26,35 @@ func TestProvider(t *testing.T) {
 	}
 }
 
+func TestProviderConfigure(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"key":             "my-key",
+		"endpoint":        "http://localhost:8080",
+		"request_timeout": 5,
+		"proxy_url":       "http://proxy.example.com:3128",
+	})
+	meta, diags := providerConfigure(context.Background(), d)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.NotNil(t, meta.(*ChtMeta).client)
+}
+
+func TestProviderConfigureMissingCABundle(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"key":            "my-key",
+		"ca_bundle_file": "/does/not/exist.pem",
+	})
+	_, diags := providerConfigure(context.Background(), d)
+	assert.True(t, diags.HasError())
+}
+
 const testAccCreateConfig = `
 resource "cloudhealth_perspective" "acc_test_owner_tag" {
   name               = "acc test owner tag"
   include_in_reports = false
 	hard_delete        = true
 
-  group {
+  dynamic_group {
     name = "OwnerAccTest"
-    type = "categorize"
 
     rule {
       asset     = "AwsAsset"
@@ -1,20 +1,27 @@
 package cloudhealth
 
 import (
-	"bytes"
+	"cloudhealth/client"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
-const apiUrl string = "https://chapi.cloudhealthtech.com/v1/perspective_schemas"
+// Values of rule_order. With "group" the rules are nested in the group they
+// belong to, with "api" they are top level and keep the order of the API.
+const (
+	groupRuleOrder = "group"
+	apiRuleOrder   = "api"
+)
 
 func resourceCHTPerspective() *schema.Resource {
 	return &schema.Resource{
@@ -23,308 +30,670 @@ func resourceCHTPerspective() *schema.Resource {
 		UpdateContext: resourceCHTPerspectiveUpdate,
 		DeleteContext: resourceCHTPerspectiveDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: resourceCHTPerspectiveImport,
 		},
-
-		Schema: map[string]*schema.Schema{
-			"name": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: false,
+		CustomizeDiff: customdiff.Sequence(
+			resourceCHTPerspectiveValidateDiff,
+			resourceCHTPerspectiveCustomizeDiff,
+		),
+
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceCHTPerspectiveV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceCHTPerspectiveStateUpgradeV0,
 			},
-			"include_in_reports": &schema.Schema{
-				Type:     schema.TypeBool,
-				Required: true,
-				ForceNew: false,
+		},
+
+		Schema: perspectiveSchema(),
+	}
+}
+
+func perspectiveSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"name": &schema.Schema{
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: false,
+		},
+		"include_in_reports": &schema.Schema{
+			Type:     schema.TypeBool,
+			Required: true,
+			ForceNew: false,
+		},
+		// Overrides the provider's client_api_id
+		"client_api_id": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			ForceNew: true,
+		},
+		// Set when the perspective was archived outside of Terraform
+		"archived": &schema.Schema{
+			Type:     schema.TypeBool,
+			Computed: true,
+		},
+		// Name of the group CloudHealth puts assets in when no rule matches
+		"other_group_name": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+			ForceNew: false,
+		},
+		"hard_delete": &schema.Schema{
+			Type:     schema.TypeBool,
+			Optional: true,
+			ForceNew: false,
+		},
+		"extra_json": extraJSONSchema(),
+		// Groups with a fixed set of rules
+		"static_group": groupSchema(false),
+		// Groups with a group for each value of a field or tag
+		"dynamic_group": groupSchema(true),
+		// The ref_id of each group key
+		"group_ref_ids": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"rule_order": &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     false,
+			Default:      groupRuleOrder,
+			ValidateFunc: validation.StringInSlice([]string{groupRuleOrder, apiRuleOrder}, false),
+		},
+		// Rules in the order CloudHealth evaluates them, for rule_order = "api"
+		"rule": &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					// name of the group the rule sends assets to
+					"group": &schema.Schema{
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: false,
+					},
+					// defaults to the type of the group; "filter" for a
+					// filter on a categorize group
+					"type": &schema.Schema{
+						Type:             schema.TypeString,
+						Optional:         true,
+						ForceNew:         false,
+						ValidateDiagFunc: stringInSlice(groupTypes),
+					},
+					"asset": &schema.Schema{
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: false,
+					},
+					// for type="categorize"
+					"tag_field": &schema.Schema{
+						Type:     schema.TypeList,
+						Optional: true,
+						ForceNew: false,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+					// for type="categorize"
+					"field": &schema.Schema{
+						Type:     schema.TypeList,
+						Optional: true,
+						ForceNew: false,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+					"combine_with": &schema.Schema{
+						Type:             schema.TypeString,
+						Optional:         true,
+						ForceNew:         false,
+						ValidateDiagFunc: stringInSlice(combineWithValues),
+						DiffSuppressFunc: suppressDefault(defaultCombineWith),
+					},
+					"condition":  conditionSchema(),
+					"extra_json": extraJSONSchema(),
+				},
 			},
-			"hard_delete": &schema.Schema{
-				Type:     schema.TypeBool,
-				Optional: true,
-				ForceNew: false,
+		},
+		"merge": &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					// The type of the constants being merged
+					"type": &schema.Schema{
+						Type:     schema.TypeString,
+						Optional: true,
+						ForceNew: false,
+						Default:  client.DynamicGroupType,
+						ValidateFunc: validation.StringInSlice([]string{
+							client.StaticGroupType,
+							client.DynamicGroupType,
+							client.DynamicGroupBlockType,
+						}, false),
+					},
+					// ref_id of the constant to merge into
+					"to": &schema.Schema{
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: false,
+					},
+					// ref_ids of the constants to merge
+					"from": &schema.Schema{
+						Type:     schema.TypeList,
+						Required: true,
+						ForceNew: false,
+						MinItems: 1,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+					"extra_json": extraJSONSchema(),
+				},
 			},
-			"group": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				ForceNew: false,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": &schema.Schema{
-							Type:     schema.TypeString,
-							Required: true,
-							ForceNew: false,
-						},
-						"ref_id": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-							Optional: true,
-						},
-						"type": &schema.Schema{
-							Type:     schema.TypeString,
-							Optional: true,
-							ForceNew: false,
-							Default:  "filter",
-						},
-						"rule": &schema.Schema{
-							Type:     schema.TypeList,
-							Optional: true,
-							ForceNew: false,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"asset": &schema.Schema{
-										Type:     schema.TypeString,
-										Required: true,
-										ForceNew: false,
-									},
-									// for type="categorize"
-									"tag_field": &schema.Schema{
-										Type:     schema.TypeList,
-										Optional: true,
-										ForceNew: false,
-										Elem:     &schema.Schema{Type: schema.TypeString},
-									},
-									// for type="categorize"
-									"field": &schema.Schema{
-										Type:     schema.TypeList,
-										Optional: true,
-										ForceNew: false,
-										Elem:     &schema.Schema{Type: schema.TypeString},
-									},
-									"combine_with": &schema.Schema{
-										Type:     schema.TypeString,
-										Optional: true,
-										ForceNew: false,
-									},
-									"condition": &schema.Schema{
-										Type:     schema.TypeList,
-										Optional: true,
-										ForceNew: false,
-										Elem: &schema.Resource{
-											Schema: map[string]*schema.Schema{
-												"tag_field": &schema.Schema{
-													Type:     schema.TypeList,
-													Optional: true,
-													ForceNew: false,
-													Elem:     &schema.Schema{Type: schema.TypeString},
-												},
-												"field": &schema.Schema{
-													Type:     schema.TypeList,
-													Optional: true,
-													ForceNew: false,
-													Elem:     &schema.Schema{Type: schema.TypeString},
-												},
-												"op": &schema.Schema{
-													Type:     schema.TypeString,
-													Optional: true,
-													ForceNew: false,
-													Default:  "=",
-												},
-												"val": &schema.Schema{
-													Type:     schema.TypeString,
-													Optional: true,
-													ForceNew: false,
-												},
-											},
-										},
-									},
-								},
-							},
-						},
+		},
+		"constant": &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"constant_type": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+					},
+					"ref_id": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+					},
+					"blk_id": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+						Optional: true,
 					},
+					"name": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+						Optional: true,
+					},
+					"val": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+						Optional: true,
+					},
+					"is_other": &schema.Schema{
+						Type:     schema.TypeString,
+						ForceNew: false,
+						Computed: true,
+						Optional: true,
+					},
+					"extra_json": extraJSONSchema(),
 				},
 			},
-			"constant": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				ForceNew: false,
-				Computed: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"constant_type": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-						},
-						"ref_id": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-						},
-						"blk_id": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-							Optional: true,
-						},
-						"name": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-							Optional: true,
-						},
-						"val": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-							Optional: true,
-						},
-						"is_other": &schema.Schema{
-							Type:     schema.TypeString,
-							ForceNew: false,
-							Computed: true,
-							Optional: true,
-						},
+		},
+	}
+}
+
+// groupSchema is the schema of static_group, or dynamic_group if dynamic
+func groupSchema(dynamic bool) *schema.Schema {
+	group := map[string]*schema.Schema{
+		"name": &schema.Schema{
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: false,
+		},
+		"ref_id": &schema.Schema{
+			Type:     schema.TypeString,
+			ForceNew: false,
+			Computed: true,
+			Optional: true,
+		},
+		// Identifies the group across renames and reorders, instead of its
+		// name
+		"key": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			ForceNew: false,
+		},
+		"rule": ruleSchema(dynamic),
+	}
+	if dynamic {
+		// Filter rules limiting which assets the group applies to
+		group["filter"] = ruleSchema(false)
+		// Display names for values of the field or tag_field
+		group["value_name"] = &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"val": &schema.Schema{
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: false,
+					},
+					"name": &schema.Schema{
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: false,
 					},
 				},
 			},
+		}
+	}
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		ForceNew: false,
+		Elem: &schema.Resource{
+			Schema: group,
+		},
+	}
+}
+
+// ruleSchema is the schema of the rules of a group. Categorize rules also say
+// which field or tag_field to categorize by.
+func ruleSchema(categorize bool) *schema.Schema {
+	rule := map[string]*schema.Schema{
+		"asset": &schema.Schema{
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: false,
+		},
+		"combine_with": &schema.Schema{
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         false,
+			ValidateDiagFunc: stringInSlice(combineWithValues),
+			DiffSuppressFunc: suppressDefault(defaultCombineWith),
+		},
+		"condition":  conditionSchema(),
+		"extra_json": extraJSONSchema(),
+	}
+	if categorize {
+		rule["tag_field"] = &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+		rule["field"] = &schema.Schema{
+			Type:     schema.TypeList,
+			Optional: true,
+			ForceNew: false,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		ForceNew: false,
+		Elem: &schema.Resource{
+			Schema: rule,
+		},
+	}
+}
+
+func conditionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		ForceNew: false,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"tag_field": &schema.Schema{
+					Type:     schema.TypeList,
+					Optional: true,
+					ForceNew: false,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"field": &schema.Schema{
+					Type:     schema.TypeList,
+					Optional: true,
+					ForceNew: false,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"op": &schema.Schema{
+					Type:             schema.TypeString,
+					Optional:         true,
+					ForceNew:         false,
+					Default:          defaultOp,
+					ValidateDiagFunc: stringInSlice(conditionOps),
+					DiffSuppressFunc: suppressDefault(defaultOp),
+				},
+				"val": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: false,
+				},
+				"extra_json": extraJSONSchema(),
+			},
 		},
 	}
 }
 
+// extraJSONSchema holds the fields CloudHealth returned that the provider
+// doesn't know about, as a JSON object. They are sent back unchanged.
+func extraJSONSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+}
+
 func resourceCHTPerspectiveCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	key := meta.(*ChtMeta).apiKey
+	c := perspectiveClient(d, meta)
 
-	pj, err := tfToJson(d)
+	pj, err := tfToPerspective(d)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	url := fmt.Sprintf("%s?api_key=%s", apiUrl, key)
-	log.Printf("Posting to Cloudhealth: url %s data, %s\n", apiUrl, string(pj))
-	resp, err := http.Post(url, "application/json", bytes.NewReader(pj))
+	id, err := c.Perspectives.Create(ctx, pj)
 	if err != nil {
-
-		body, _ := ioutil.ReadAll(resp.Body)
-		bodyStr := string(body)
-		log.Println("Response to Cloudhealth POST is:", bodyStr)
-		return diag.FromErr(fmt.Errorf("Failed to create perspective because %s, response %s", err, bodyStr))
+		return apiErrorDiagnostics("Failed to create perspective", err, perspectiveRulePaths(d))
 	}
-	defer resp.Body.Close()
+	log.Printf("[INFO] Created Cloudhealth perspective %s", id)
+	d.SetId(id)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		bodyStr := string(body)
-		log.Println("Response to Cloudhealth POST is:", bodyStr)
-		return diag.FromErr(fmt.Errorf("Failed to create perspective %s because got status code %d", d.Id(), resp.StatusCode))
+	// We need to set the constants field to what cloudhealth thinks it is, as
+	// its computed we need to read it back from cloudhealth
+	otherName := d.Get("other_group_name").(string)
+	diags := readBackPerspective(ctx, d, meta, pj)
+	if diags.HasError() || d.Id() == "" {
+		return diags
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to create perspective because %s", err))
+	// Cloudhealth only adds the "Other" group once the perspective exists, so
+	// it has to be renamed by an update
+	if otherName != "" && d.Get("other_group_name").(string) != otherName {
+		d.Set("other_group_name", otherName)
+		diags = append(diags, resourceCHTPerspectiveUpdate(ctx, d, meta)...)
 	}
-	re := regexp.MustCompile(`Perspective (\d*) created`)
-	match := re.FindStringSubmatch(string(body))
-	if match == nil || len(match) != 2 {
-		return diag.FromErr(fmt.Errorf("Created perspective but didn't understand response to extract ID: %s", body))
-	}
-	bodyStr := string(body)
-	log.Println("[INFO] Response to Cloudhealth POST is:", bodyStr)
-	d.SetId(match[1])
-
-	// We need to set the constants field to what cloudhealth thinks it is, as
-	// its computed we need to read it back from cloudhealth - easiest to do that
-	// by using the read method
-	return resourceCHTPerspectiveRead(ctx, d, meta)
+	return diags
 }
 
 func resourceCHTPerspectiveRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	key := meta.(*ChtMeta).apiKey
+	_, diags := readPerspective(ctx, d, meta)
+	return diags
+}
 
-	id, err := strconv.Atoi(d.Id())
+// readPerspective loads the perspective into d and returns it as Cloudhealth
+// has it. Returns nil if it no longer exists or is archived.
+func readPerspective(ctx context.Context, d *schema.ResourceData, meta interface{}) (*client.PerspectiveJSON, diag.Diagnostics) {
+	c := perspectiveClient(d, meta)
+
+	id, err := perspectiveID(d)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to parse %s as int because %s", d.Id(), err))
+		return nil, diag.FromErr(err)
 	}
 
-	url := fmt.Sprintf("%s/%d?api_key=%s", apiUrl, id, key)
-	resp, err := http.Get(url)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to load perspective %s because %s", d.Id(), err))
+	pj, err := c.Perspectives.Get(ctx, id)
+	if client.IsArchived(err) {
+		// Keep it in state so that the plan shows it being replaced
+		log.Printf("[WARN] Cloudhealth perspective %s is archived", d.Id())
+		d.Set("archived", true)
+		return nil, diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Perspective %s has been archived", d.Id()),
+			Detail:   "The perspective was archived outside of Terraform. Terraform will replace it with a new perspective.",
+		}}
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		bodyStr := string(body)
-		log.Println("Response from Cloudhealth is:", bodyStr)
-		return diag.FromErr(fmt.Errorf("Failed to load perspective %s because got status code %d", d.Id(), resp.StatusCode))
+	if client.IsNotFound(err) {
+		log.Printf("[WARN] Cloudhealth perspective %s not found, removing from state", id)
+		d.SetId("")
+		return nil, diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Perspective %s no longer exists", id),
+			Detail:   "The perspective was deleted outside of Terraform and has been removed from the state. Terraform will create it again.",
+		}}
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to read perspective %s because %s", d.Id(), err))
+		return nil, apiErrorDiagnostics(fmt.Sprintf("Failed to load perspective %s", d.Id()), err, nil)
 	}
 
-	err = jsonToTF(body, d)
+	err = perspectiveToTF(pj, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, diag.FromErr(err)
 	}
-	return nil
+	d.Set("archived", false)
+	return pj, nil
 }
 
-func resourceCHTPerspectiveUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	key := meta.(*ChtMeta).apiKey
-	pj, err := tfToJson(d)
-	if err != nil {
-		return diag.FromErr(err)
+// readBackPerspective refreshes the state after sending a perspective to
+// Cloudhealth, so that the constants it computes are up to date. It warns if
+// Cloudhealth did not store what was sent.
+func readBackPerspective(ctx context.Context, d *schema.ResourceData, meta interface{}, sent *client.PerspectiveJSON) diag.Diagnostics {
+	stored, diags := readPerspective(ctx, d, meta)
+	if diags.HasError() || stored == nil {
+		return diags
 	}
 
-	ioutil.WriteFile(fmt.Sprintf("cht_update-%s.json", d.Id()), pj, 0644)
+	if differences := perspectiveDifferences(sent, stored); len(differences) > 0 {
+		log.Printf("[WARN] Cloudhealth perspective %s differs from what was sent: %s", d.Id(), strings.Join(differences, "; "))
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Perspective %s was not stored as sent", d.Id()),
+			Detail:   "Cloudhealth stored a different perspective than the one Terraform sent:\n" + strings.Join(differences, "\n"),
+		})
+	}
+	return diags
+}
 
-	id, err := strconv.Atoi(d.Id())
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to parse %s as int because %s", d.Id(), err))
+// perspectiveDifferences compares the parts of a perspective that Terraform
+// controls. Constants that Cloudhealth computes, such as dynamic group values
+// and the "Other" group, are ignored.
+func perspectiveDifferences(sent *client.PerspectiveJSON, stored *client.PerspectiveJSON) (differences []string) {
+	if sent.Schema.Name != stored.Schema.Name {
+		differences = append(differences, fmt.Sprintf("name is %q, expected %q", stored.Schema.Name, sent.Schema.Name))
+	}
+	if sent.Schema.Include_in_reports != stored.Schema.Include_in_reports {
+		differences = append(differences, fmt.Sprintf("include_in_reports is %s, expected %s", stored.Schema.Include_in_reports, sent.Schema.Include_in_reports))
 	}
 
-	url := fmt.Sprintf("%s/%d?api_key=%s", apiUrl, id, key)
+	if len(sent.Schema.Rules) != len(stored.Schema.Rules) {
+		differences = append(differences, fmt.Sprintf("has %d rules, expected %d", len(stored.Schema.Rules), len(sent.Schema.Rules)))
+	} else {
+		for idx := range sent.Schema.Rules {
+			sentRule, _ := json.Marshal(ruleWithDefaults(sent.Schema.Rules[idx]))
+			storedRule, _ := json.Marshal(ruleWithDefaults(stored.Schema.Rules[idx]))
+			if string(sentRule) != string(storedRule) {
+				differences = append(differences, fmt.Sprintf("rule %d is %s, expected %s", idx, storedRule, sentRule))
+			}
+		}
+	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(pj))
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to update perspective %s because %s", d.Id(), err))
+	// No merges may come back as null or []
+	sentMerges, _ := json.Marshal(sent.Schema.Merges)
+	storedMerges, _ := json.Marshal(stored.Schema.Merges)
+	if string(sentMerges) != string(storedMerges) && (len(sent.Schema.Merges) > 0 || len(stored.Schema.Merges) > 0) {
+		differences = append(differences, fmt.Sprintf("merges are %s, expected %s", storedMerges, sentMerges))
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to update perspective %s because %s", d.Id(), err))
+	if sentOther := otherGroupConstant(sent); sentOther != nil {
+		storedOther := otherGroupConstant(stored)
+		if storedOther == nil {
+			differences = append(differences, fmt.Sprintf("has no other group, expected one named %q", sentOther.Name))
+		} else if storedOther.Name != sentOther.Name {
+			differences = append(differences, fmt.Sprintf("other group is named %q, expected %q", storedOther.Name, sentOther.Name))
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		bodyStr := string(body)
-		log.Println("Response to Cloudhealth PUT is:", bodyStr)
-		return diag.FromErr(fmt.Errorf("Got status code %d when attempting to update perspective: %s\n%s", resp.StatusCode, d.Id(), bodyStr))
+	sentGroups := groupConstantNames(sent)
+	storedGroups := groupConstantNames(stored)
+	for refId, name := range sentGroups {
+		if storedGroups[refId] != name {
+			differences = append(differences, fmt.Sprintf("group %s is named %q, expected %q", refId, storedGroups[refId], name))
+		}
 	}
+	for refId, name := range storedGroups {
+		if _, ok := sentGroups[refId]; !ok {
+			differences = append(differences, fmt.Sprintf("has unexpected group %s named %q", refId, name))
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
+
+// groupConstantNames maps the ref_id of each group to its name
+func groupConstantNames(pj *client.PerspectiveJSON) map[string]string {
+	result := make(map[string]string)
+	for _, constant := range pj.Schema.Constants {
+		if constant.Type != client.StaticGroupType && constant.Type != client.DynamicGroupBlockType {
+			continue
+		}
+		for _, item := range constant.List {
+			if item.Is_other == "true" {
+				continue
+			}
+			result[item.Ref_id] = item.Name
+		}
+	}
+	return result
+}
 
+// otherGroupConstant finds the "Other" group that Cloudhealth adds to every
+// perspective. Returns nil if there isn't one.
+func otherGroupConstant(pj *client.PerspectiveJSON) *client.ConstantItem {
+	for _, constant := range pj.Schema.Constants {
+		for idx := range constant.List {
+			if constant.List[idx].Is_other == "true" {
+				return &constant.List[idx]
+			}
+		}
+	}
 	return nil
 }
 
-func resourceCHTPerspectiveDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	key := meta.(*ChtMeta).apiKey
+func resourceCHTPerspectiveUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+	pj, err := tfToPerspective(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-	id, err := strconv.Atoi(d.Id())
+	id, err := perspectiveID(d)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to parse %s as int because %s", d.Id(), err))
+		return diag.FromErr(err)
 	}
 
-	hard_delete := d.Get("hard_delete")
-	url := fmt.Sprintf("%s/%d?api_key=%s&hard_delete=%t", apiUrl, id, key, hard_delete)
+	err = c.Perspectives.Update(ctx, id, pj)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to update perspective %s", d.Id()), err, perspectiveRulePaths(d))
+	}
+
+	// Like create, read back what cloudhealth computed so that the state
+	// doesn't hold stale constants until the next refresh
+	return readBackPerspective(ctx, d, meta, pj)
+}
 
-	req, err := http.NewRequest("DELETE", url, nil)
+func resourceCHTPerspectiveDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := perspectiveClient(d, meta)
+
+	id, err := perspectiveID(d)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to delete perspective %s because %s", d.Id(), err))
+		return diag.FromErr(err)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	hardDelete := d.Get("hard_delete").(bool)
+	err = c.Perspectives.Delete(ctx, id, hardDelete)
+	if client.IsNotFound(err) {
+		// Already gone
+		return nil
+	}
+	if client.IsArchived(err) {
+		// Gone as far as Terraform is concerned. Failing here would also
+		// block the replacement of a perspective archived outside Terraform.
+		if hardDelete {
+			log.Printf("[WARN] Cloudhealth perspective %s is archived and can't be hard deleted, leaving it archived", d.Id())
+		}
+		return nil
+	}
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("Failed to delete perspective %s because %s", d.Id(), err))
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to delete perspective %s", d.Id()), err, nil)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return diag.FromErr(fmt.Errorf("Failed to delete perspective %s because got status code %d", d.Id(), resp.StatusCode))
+	return nil
+}
+
+func resourceCHTPerspectiveCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	// An archived perspective can't be updated, so replace it
+	if d.Id() != "" && d.Get("archived").(bool) {
+		if err := d.SetNew("archived", false); err != nil {
+			return err
+		}
+		return d.ForceNew("archived")
 	}
 
+	// New keys get their ref_id on apply, and removed ones are forgotten
+	refIdByKey := d.Get("group_ref_ids").(map[string]interface{})
+	configured := make(map[string]interface{})
+	for _, block := range []string{"static_group", "dynamic_group"} {
+		for groupIdx, g := range d.Get(block).([]interface{}) {
+			key := g.(map[string]interface{})["key"].(string)
+			if !d.NewValueKnown(fmt.Sprintf("%s.%d.key", block, groupIdx)) {
+				return d.SetNewComputed("group_ref_ids")
+			}
+			if key == "" {
+				continue
+			}
+			refId, ok := refIdByKey[key]
+			if !ok {
+				return d.SetNewComputed("group_ref_ids")
+			}
+			configured[key] = refId
+		}
+	}
+	if len(configured) != len(refIdByKey) {
+		return d.SetNew("group_ref_ids", configured)
+	}
 	return nil
 }
+
+// resourceCHTPerspectiveImport accepts either a perspective ID or, for
+// partners, <client_api_id>:<perspective_id>. client_api_id is only recorded
+// when it differs from the provider's, so that a configuration relying on
+// the provider's doesn't plan to replace the perspective.
+func resourceCHTPerspectiveImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), ":")
+	switch len(parts) {
+	case 1:
+	case 2:
+		if parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid import ID %s. Expected <perspective_id> or <client_api_id>:<perspective_id>", d.Id())
+		}
+		if m, ok := meta.(*ChtMeta); !ok || m.client.ClientAPIID() != parts[0] {
+			err := d.Set("client_api_id", parts[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		d.SetId(parts[1])
+	default:
+		return nil, fmt.Errorf("Invalid import ID %s. Expected <perspective_id> or <client_api_id>:<perspective_id>", d.Id())
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// perspectiveClient returns the API client for the tenant this perspective
+// lives in
+func perspectiveClient(d *schema.ResourceData, meta interface{}) *client.Client {
+	c := meta.(*ChtMeta).client
+	if clientAPIID, ok := d.GetOk("client_api_id"); ok {
+		return c.WithClientAPIID(clientAPIID.(string))
+	}
+	return c
+}
+
+// perspectiveID checks that the resource ID is a valid perspective ID
+func perspectiveID(d *schema.ResourceData) (string, error) {
+	if _, err := strconv.Atoi(d.Id()); err != nil {
+		return "", fmt.Errorf("Failed to parse %s as int because %s", d.Id(), err)
+	}
+	return d.Id(), nil
+}
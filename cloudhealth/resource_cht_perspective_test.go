@@ -0,0 +1,516 @@
+package cloudhealth
+
+import (
+	"cloudhealth/client"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMeta returns provider metadata whose client talks to handler
+func newTestMeta(t *testing.T, handler http.HandlerFunc) *ChtMeta {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(client.Config{
+		Endpoint: server.URL,
+		APIKey:   "key",
+	})
+	assert.Nil(t, err)
+	return &ChtMeta{client: c}
+}
+
+func TestImportWithClientAPIID(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("9876:1234")
+
+	result, err := resource.Importer.StateContext(context.Background(), rd, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "1234", result[0].Id())
+	assertEqual(t, result[0], "client_api_id", "9876")
+}
+
+func TestImportWithProviderClientAPIIDPlansNoReplace(t *testing.T) {
+	c, err := client.New(client.Config{APIKey: "key", ClientAPIID: "9876"})
+	assert.Nil(t, err)
+	meta := &ChtMeta{client: c}
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("9876:1234")
+	result, err := resource.Importer.StateContext(context.Background(), rd, meta)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", result[0].Id())
+	assertEqual(t, result[0], "client_api_id", "")
+
+	// The configuration takes client_api_id from the provider
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+	})
+	diff, err := resource.Diff(context.Background(), result[0].State(), config, meta)
+	assert.Nil(t, err)
+	assert.False(t, diff.RequiresNew())
+	_, ok := diff.Attributes["client_api_id"]
+	assert.False(t, ok)
+}
+
+func TestImportPlainID(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+
+	result, err := resource.Importer.StateContext(context.Background(), rd, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", result[0].Id())
+	assertEqual(t, result[0], "client_api_id", "")
+}
+
+func TestImportInvalidID(t *testing.T) {
+	resource := resourceCHTPerspective()
+	for _, id := range []string{":1234", "9876:", "1:2:3"} {
+		rd := resource.TestResourceData()
+		rd.SetId(id)
+		_, err := resource.Importer.StateContext(context.Background(), rd, nil)
+		assert.NotNil(t, err, id)
+	}
+}
+
+func TestReadUsesClientAPIIDOverride(t *testing.T) {
+	var tenant string
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.URL.Query().Get("client_api_id")
+		http.ServeFile(w, r, "../test/static_perspective.json")
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+	assert.Nil(t, rd.Set("client_api_id", "9876"))
+
+	diags := resource.ReadContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, "9876", tenant)
+	assertEqual(t, rd, "name", "My Name")
+}
+
+func TestReadNotFound(t *testing.T) {
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Record not found"}`))
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+
+	diags := resource.ReadContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Equal(t, "", rd.Id())
+}
+
+func TestReadArchived(t *testing.T) {
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"error": "Perspective has been archived"}`))
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+
+	diags := resource.ReadContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Equal(t, "1234", rd.Id())
+	assertEqual(t, rd, "archived", true)
+}
+
+func TestArchivedPerspectiveIsReplaced(t *testing.T) {
+	resource := resourceCHTPerspective()
+	state := &terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":               "My Name",
+			"include_in_reports": "true",
+			"archived":           "true",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+	})
+
+	diff, err := resource.Diff(context.Background(), state, config, nil)
+	assert.Nil(t, err)
+	assert.True(t, diff.RequiresNew())
+
+	state.Attributes["archived"] = "false"
+	diff, err = resource.Diff(context.Background(), state, config, nil)
+	assert.Nil(t, err)
+	assert.False(t, diff.RequiresNew())
+}
+
+func TestHardDeleteArchived(t *testing.T) {
+	var query string
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"error": "Perspective has been archived"}`))
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+	rd.Set("hard_delete", true)
+
+	diags := resource.DeleteContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, "hard_delete=true", query)
+}
+
+func TestDeleteAlreadyGone(t *testing.T) {
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+
+	diags := resource.DeleteContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+}
+
+func TestUpdateReadsBackConstants(t *testing.T) {
+	var stored []byte
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			var pj client.PerspectiveJSON
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&pj))
+			// Cloudhealth adds an "Other" group
+			pj.Schema.Constants[0].List = append(pj.Schema.Constants[0].List, client.ConstantItem{
+				Ref_id: "9", Name: "Other", Is_other: "true",
+			})
+			stored, _ = json.Marshal(pj)
+		case http.MethodGet:
+			w.Write(stored)
+		}
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                                  "My Name",
+			"include_in_reports":                    "true",
+			"static_group.#":                        "1",
+			"static_group.0.name":                   "New Group",
+			"static_group.0.rule.#":                 "1",
+			"static_group.0.rule.0.asset":           "AwsAccount",
+			"static_group.0.rule.0.condition.#":     "1",
+			"static_group.0.rule.0.condition.0.op":  "=",
+			"static_group.0.rule.0.condition.0.val": "My Account",
+		},
+	})
+
+	diags := resource.UpdateContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assertEqual(t, rd, "constant.#", 2)
+	assertEqual(t, rd, "constant.1.name", "Other")
+	assertEqual(t, rd, "constant.1.is_other", "true")
+}
+
+func TestUpdateWarnsWhenNotStoredAsSent(t *testing.T) {
+	var stored []byte
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			var pj client.PerspectiveJSON
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&pj))
+			pj.Schema.Rules[0].Condition.Clauses[0].Val = "Something Else"
+			stored, _ = json.Marshal(pj)
+		case http.MethodGet:
+			w.Write(stored)
+		}
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+	originalBytes, err := ioutil.ReadFile("../test/static_perspective.json")
+	assert.Nil(t, err)
+	assert.Nil(t, jsonToTF(originalBytes, rd))
+
+	diags := resource.UpdateContext(context.Background(), rd, meta)
+	assert.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Contains(t, diags[0].Detail, "Something Else")
+	assertEqual(t, rd, "static_group.0.rule.0.condition.0.val", "Something Else")
+}
+
+func TestPerspectiveDifferences(t *testing.T) {
+	originalBytes, err := ioutil.ReadFile("../test/dynamic_perspective.json")
+	assert.Nil(t, err)
+	sent, err := client.DecodePerspective(originalBytes)
+	assert.Nil(t, err)
+	stored, err := client.DecodePerspective(originalBytes)
+	assert.Nil(t, err)
+
+	// Computed constants are not compared
+	stored.Schema.Constants[1].List = nil
+	assert.Empty(t, perspectiveDifferences(sent, stored))
+
+	stored.Schema.Name = "Renamed"
+	stored.Schema.Constants[2].List[0].Name = "Renamed Group"
+	stored.Schema.Rules = stored.Schema.Rules[:1]
+	assert.Equal(t, []string{
+		`group 1 is named "Renamed Group", expected "Group One"`,
+		`has 1 rules, expected 2`,
+		`name is "Renamed", expected "My Dynamic"`,
+	}, perspectiveDifferences(sent, stored))
+}
+
+func TestPerspectiveDifferencesIgnoreDefaults(t *testing.T) {
+	originalBytes, err := ioutil.ReadFile("../test/dynamic_perspective.json")
+	assert.Nil(t, err)
+	sent, err := client.DecodePerspective(originalBytes)
+	assert.Nil(t, err)
+	stored, err := client.DecodePerspective(originalBytes)
+	assert.Nil(t, err)
+
+	// Sent without defaults, stored with them filled in
+	sent.Schema.Rules[0].Condition.Combine_with = ""
+	stored.Schema.Rules[0].Condition.Combine_with = "OR"
+	sent.Schema.Rules[0].Condition.Clauses[0].Op = ""
+	stored.Schema.Rules[0].Condition.Clauses[0].Op = "="
+	sent.Schema.Rules[1].Tag_field = []string{}
+	stored.Schema.Rules[1].Tag_field = nil
+	sent.Schema.Merges = []client.MergeJSON{}
+	stored.Schema.Merges = nil
+	assert.Empty(t, perspectiveDifferences(sent, stored))
+	assert.Equal(t, "", sent.Schema.Rules[0].Condition.Combine_with, "sent is not modified")
+
+	stored.Schema.Rules[0].Condition.Combine_with = "AND"
+	assert.Equal(t, 1, len(perspectiveDifferences(sent, stored)))
+}
+
+func TestUnknownFieldsAreSentBackOnUpdate(t *testing.T) {
+	originalBytes, err := ioutil.ReadFile("../test/unknown_fields_perspective.json")
+	assert.Nil(t, err)
+
+	var sent []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			sent, _ = ioutil.ReadAll(r.Body)
+		}
+		w.Write(originalBytes)
+	}))
+	t.Cleanup(server.Close)
+	c, err := client.New(client.Config{
+		Endpoint:          server.URL,
+		APIKey:            "key",
+		KeepUnknownFields: true,
+	})
+	assert.Nil(t, err)
+	meta := &ChtMeta{client: c}
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+	diags := resource.ReadContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+
+	diags = resource.UpdateContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assertJsonEqual(t, originalBytes, sent)
+}
+
+func TestNewGroupKeyPlansGroupRefIDs(t *testing.T) {
+	resource := resourceCHTPerspective()
+	state := &terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                  "My Name",
+			"include_in_reports":    "true",
+			"static_group.#":        "1",
+			"static_group.0.name":   "A",
+			"static_group.0.ref_id": "1",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "A", "key": "a"},
+		},
+	})
+
+	diff, err := resource.Diff(context.Background(), state, config, nil)
+	assert.Nil(t, err)
+	assert.True(t, diff.Attributes["group_ref_ids.%"].NewComputed)
+}
+
+func TestGroupKeyRemovedAndReAdded(t *testing.T) {
+	resource := resourceCHTPerspective()
+	state := &terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                     "My Name",
+			"include_in_reports":       "true",
+			"rule_order":               "group",
+			"group_ref_ids.%":          "2",
+			"group_ref_ids.a":          "1",
+			"group_ref_ids.b":          "2",
+			"static_group.#":           "2",
+			"static_group.0.name":      "A",
+			"static_group.0.key":       "a",
+			"static_group.0.ref_id":    "1",
+			"static_group.1.name":      "B",
+			"static_group.1.key":       "b",
+			"static_group.1.ref_id":    "2",
+			"constant.#":               "2",
+			"constant.0.constant_type": "Static Group",
+			"constant.0.name":          "A",
+			"constant.0.ref_id":        "1",
+			"constant.1.constant_type": "Static Group",
+			"constant.1.name":          "B",
+			"constant.1.ref_id":        "2",
+		},
+	}
+	apply := func(state *terraform.InstanceState, groups []interface{}) *schema.ResourceData {
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"name":               "My Name",
+			"include_in_reports": true,
+			"static_group":       groups,
+		})
+		diff, err := resource.Diff(context.Background(), state, config, nil)
+		assert.Nil(t, err)
+		rd, err := schema.InternalMap(resource.Schema).Data(state, diff)
+		assert.Nil(t, err)
+		planned := rd.Get("group_ref_ids")
+		_, err = tfToJson(rd)
+		assert.Nil(t, err)
+		// Terraform fails the apply if a known planned value changes
+		if attr, ok := diff.Attributes["group_ref_ids.%"]; !ok || !attr.NewComputed {
+			assertEqual(t, rd, "group_ref_ids", planned)
+		}
+		return rd
+	}
+
+	// The key of group A is removed, so the plan forgets it
+	rd := apply(state, []interface{}{
+		map[string]interface{}{"name": "A"},
+		map[string]interface{}{"name": "B", "key": "b"},
+	})
+	assertEqual(t, rd, "static_group.0.ref_id", "1")
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"b": "2"})
+
+	// Re-adding the key on a new group doesn't take the ref_id of group A
+	rd = apply(rd.State(), []interface{}{
+		map[string]interface{}{"name": "A"},
+		map[string]interface{}{"name": "B", "key": "b"},
+		map[string]interface{}{"name": "C", "key": "a"},
+	})
+	assertEqual(t, rd, "static_group.0.ref_id", "1")
+	assertEqual(t, rd, "static_group.2.ref_id", "3")
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"a": "3", "b": "2"})
+}
+
+func TestValueNamesReadBackPlanNoChanges(t *testing.T) {
+	originalBytes, err := ioutil.ReadFile("../test/value_name_perspective.json")
+	assert.Nil(t, err)
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.SetId("1234")
+	rd.Set("rule_order", "group")
+	rd.Set("group_ref_ids", map[string]interface{}{})
+	rd.Set("dynamic_group", []interface{}{
+		map[string]interface{}{
+			"name":   "Team",
+			"ref_id": "1",
+			"value_name": []interface{}{
+				map[string]interface{}{"val": "team-web", "name": "Web"},
+				map[string]interface{}{"val": "team-infra-sre", "name": "Infra SRE"},
+			},
+		},
+	})
+	assert.Nil(t, jsonToTF(originalBytes, rd))
+
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Teams",
+		"include_in_reports": true,
+		"dynamic_group": []interface{}{
+			map[string]interface{}{
+				"name": "Team",
+				"rule": []interface{}{
+					map[string]interface{}{"asset": "AwsAsset", "tag_field": []interface{}{"team"}},
+				},
+				"value_name": []interface{}{
+					map[string]interface{}{"val": "team-web", "name": "Web"},
+					map[string]interface{}{"val": "team-infra-sre", "name": "Infra SRE"},
+				},
+			},
+		},
+	})
+	diff, err := resource.Diff(context.Background(), rd.State(), config, nil)
+	assert.Nil(t, err)
+	assert.True(t, diff == nil || diff.Empty(), "%v", diff)
+}
+
+func TestCreateRenamesOtherGroup(t *testing.T) {
+	var stored []byte
+	puts := 0
+	meta := newTestMeta(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var pj client.PerspectiveJSON
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&pj))
+			// Cloudhealth adds an "Other" group
+			pj.Schema.Constants[0].List = append(pj.Schema.Constants[0].List, client.ConstantItem{
+				Ref_id: "9", Name: "Other", Is_other: "true",
+			})
+			stored, _ = json.Marshal(pj)
+			w.Write([]byte(`{"message": "Perspective 1234 created"}`))
+		case http.MethodPut:
+			puts++
+			stored, _ = ioutil.ReadAll(r.Body)
+		case http.MethodGet:
+			w.Write(stored)
+		}
+	})
+
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	rd.Set("name", "My Name")
+	rd.Set("other_group_name", "Unallocated - needs tagging")
+	rd.Set("static_group", []interface{}{
+		map[string]interface{}{
+			"name": "New Group",
+			"rule": []interface{}{
+				map[string]interface{}{"asset": "AwsAccount"},
+			},
+		},
+	})
+
+	diags := resource.CreateContext(context.Background(), rd, meta)
+	assert.Equal(t, 0, len(diags), "%v", diags)
+	assert.Equal(t, 1, puts)
+	assertEqual(t, rd, "other_group_name", "Unallocated - needs tagging")
+	assertEqual(t, rd, "constant.1.ref_id", "9")
+	assertEqual(t, rd, "constant.1.name", "Unallocated - needs tagging")
+}
@@ -0,0 +1,330 @@
+package cloudhealth
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceCHTPerspectiveV0 is the schema before static_group and
+// dynamic_group, when all groups were group blocks with a type of "filter" or
+// "categorize". Only used to read old state, so it is frozen as it was and
+// must not change with the current schema.
+func resourceCHTPerspectiveV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"include_in_reports": &schema.Schema{
+				Type:     schema.TypeBool,
+				Required: true,
+			},
+			"client_api_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"archived": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"hard_delete": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"extra_json": extraJSONSchemaV0(),
+			"group": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"ref_id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+							Optional: true,
+						},
+						"key": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"type": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "filter",
+						},
+						"filter": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"asset": &schema.Schema{
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"combine_with": &schema.Schema{
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"condition":  conditionSchemaV0(),
+									"extra_json": extraJSONSchemaV0(),
+								},
+							},
+						},
+						"rule": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"asset": &schema.Schema{
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"tag_field": stringListSchemaV0(),
+									"field":     stringListSchemaV0(),
+									"combine_with": &schema.Schema{
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"condition":  conditionSchemaV0(),
+									"extra_json": extraJSONSchemaV0(),
+								},
+							},
+						},
+					},
+				},
+			},
+			"group_ref_ids": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"rule_order": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "group",
+			},
+			"rule": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"group": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"type": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"asset": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"tag_field": stringListSchemaV0(),
+						"field":     stringListSchemaV0(),
+						"combine_with": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"condition":  conditionSchemaV0(),
+						"extra_json": extraJSONSchemaV0(),
+					},
+				},
+			},
+			"merge": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "Dynamic Group",
+						},
+						"to": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"from": &schema.Schema{
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"extra_json": extraJSONSchemaV0(),
+					},
+				},
+			},
+			"constant": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"constant_type": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ref_id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"blk_id":     optionalComputedStringV0(),
+						"name":       optionalComputedStringV0(),
+						"val":        optionalComputedStringV0(),
+						"is_other":   optionalComputedStringV0(),
+						"extra_json": extraJSONSchemaV0(),
+					},
+				},
+			},
+		},
+	}
+}
+
+func conditionSchemaV0() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"tag_field": stringListSchemaV0(),
+				"field":     stringListSchemaV0(),
+				"op": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "=",
+				},
+				"val": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"extra_json": extraJSONSchemaV0(),
+			},
+		},
+	}
+}
+
+func stringListSchemaV0() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+}
+
+func optionalComputedStringV0() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+	}
+}
+
+func extraJSONSchemaV0() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+}
+
+// resourceCHTPerspectiveStateUpgradeV0 moves each group to static_group or
+// dynamic_group by its type.
+//
+// Version 1 sends the rules of all static groups before those of dynamic
+// groups. Where the old state interleaved them, its rules become top level
+// rules with rule_order = "api", so that CloudHealth still gets them in the
+// same order.
+func resourceCHTPerspectiveStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	groups, _ := rawState["group"].([]interface{})
+	delete(rawState, "group")
+
+	if rawState["rule_order"] != apiRuleOrder && !staticRulesFirst(groups) {
+		rules, _ := rawState["rule"].([]interface{})
+		for _, g := range groups {
+			g := g.(map[string]interface{})
+			if g["type"] == "categorize" {
+				rules = append(rules, groupRulesToTopLevel(g, "filter", "filter")...)
+				rules = append(rules, groupRulesToTopLevel(g, "rule", "")...)
+			} else {
+				rules = append(rules, groupRulesToTopLevel(g, "rule", "")...)
+			}
+			g["filter"] = []interface{}{}
+			g["rule"] = []interface{}{}
+		}
+		rawState["rule"] = rules
+		rawState["rule_order"] = apiRuleOrder
+	}
+
+	staticGroups := make([]interface{}, 0)
+	dynamicGroups := make([]interface{}, 0)
+	for _, g := range groups {
+		g := g.(map[string]interface{})
+		groupType := g["type"]
+		delete(g, "type")
+		if groupType == "categorize" {
+			dynamicGroups = append(dynamicGroups, g)
+			continue
+		}
+		delete(g, "filter")
+		rules, _ := g["rule"].([]interface{})
+		for _, r := range rules {
+			r := r.(map[string]interface{})
+			delete(r, "field")
+			delete(r, "tag_field")
+		}
+		staticGroups = append(staticGroups, g)
+	}
+	rawState["static_group"] = staticGroups
+	rawState["dynamic_group"] = dynamicGroups
+	return rawState, nil
+}
+
+// staticRulesFirst returns true if no static group with rules comes after a
+// dynamic group with rules, so that version 1 sends the rules in the same
+// order
+func staticRulesFirst(groups []interface{}) bool {
+	seenDynamic := false
+	for _, g := range groups {
+		g := g.(map[string]interface{})
+		filters, _ := g["filter"].([]interface{})
+		rules, _ := g["rule"].([]interface{})
+		if len(filters)+len(rules) == 0 {
+			continue
+		}
+		if g["type"] == "categorize" {
+			seenDynamic = true
+		} else if seenDynamic {
+			return false
+		}
+	}
+	return true
+}
+
+// groupRulesToTopLevel converts the rules in the block of a version 0 group to
+// top level rules naming it
+func groupRulesToTopLevel(group map[string]interface{}, block string, ruleType string) []interface{} {
+	blockRules, _ := group[block].([]interface{})
+	result := make([]interface{}, 0, len(blockRules))
+	for _, r := range blockRules {
+		rule := make(map[string]interface{})
+		for k, v := range r.(map[string]interface{}) {
+			rule[k] = v
+		}
+		rule["group"] = group["name"]
+		rule["type"] = ruleType
+		if _, ok := rule["field"]; !ok {
+			rule["field"] = []interface{}{}
+		}
+		if _, ok := rule["tag_field"]; !ok {
+			rule["tag_field"] = []interface{}{}
+		}
+		result = append(result, rule)
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package cloudhealth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func v0Rule(asset string) map[string]interface{} {
+	return map[string]interface{}{
+		"asset":        asset,
+		"combine_with": "",
+		"condition":    []interface{}{},
+		"extra_json":   "",
+		"field":        []interface{}{},
+		"tag_field":    []interface{}{"team"},
+	}
+}
+
+func TestStateUpgradeV0SplitsGroups(t *testing.T) {
+	rawState := map[string]interface{}{
+		"id":         "1234",
+		"name":       "My Name",
+		"rule_order": "group",
+		"group": []interface{}{
+			map[string]interface{}{
+				"name":   "Static",
+				"ref_id": "1",
+				"type":   "filter",
+				"filter": []interface{}{},
+				"rule":   []interface{}{v0Rule("AwsAccount")},
+			},
+			map[string]interface{}{
+				"name":   "Teams",
+				"ref_id": "2",
+				"type":   "categorize",
+				"filter": []interface{}{v0Rule("AwsAsset")},
+				"rule":   []interface{}{v0Rule("AwsAsset")},
+			},
+		},
+	}
+
+	state, err := resourceCHTPerspectiveStateUpgradeV0(context.Background(), rawState, nil)
+	assert.Nil(t, err)
+	assert.NotContains(t, state, "group")
+	assert.Equal(t, "group", state["rule_order"])
+
+	staticGroups := state["static_group"].([]interface{})
+	assert.Equal(t, 1, len(staticGroups))
+	static := staticGroups[0].(map[string]interface{})
+	assert.Equal(t, "Static", static["name"])
+	assert.NotContains(t, static, "type")
+	assert.NotContains(t, static, "filter")
+	assert.NotContains(t, static["rule"].([]interface{})[0], "tag_field")
+
+	dynamicGroups := state["dynamic_group"].([]interface{})
+	assert.Equal(t, 1, len(dynamicGroups))
+	dynamic := dynamicGroups[0].(map[string]interface{})
+	assert.Equal(t, "Teams", dynamic["name"])
+	assert.NotContains(t, dynamic, "type")
+	assert.Equal(t, 1, len(dynamic["filter"].([]interface{})))
+	assert.Equal(t, []interface{}{"team"}, dynamic["rule"].([]interface{})[0].(map[string]interface{})["tag_field"])
+}
+
+func TestStateUpgradeV0KeepsInterleavedRuleOrder(t *testing.T) {
+	rawState := map[string]interface{}{
+		"id":         "1234",
+		"name":       "My Name",
+		"rule_order": "group",
+		"group": []interface{}{
+			map[string]interface{}{
+				"name":   "Teams",
+				"ref_id": "1",
+				"type":   "categorize",
+				"filter": []interface{}{v0Rule("AwsAsset")},
+				"rule":   []interface{}{v0Rule("AwsAsset")},
+			},
+			map[string]interface{}{
+				"name":   "Static",
+				"ref_id": "2",
+				"type":   "filter",
+				"filter": []interface{}{},
+				"rule":   []interface{}{v0Rule("AwsAccount")},
+			},
+		},
+	}
+
+	state, err := resourceCHTPerspectiveStateUpgradeV0(context.Background(), rawState, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "api", state["rule_order"])
+
+	rules := state["rule"].([]interface{})
+	assert.Equal(t, 3, len(rules))
+	expected := []struct{ group, ruleType, asset string }{
+		{"Teams", "filter", "AwsAsset"},
+		{"Teams", "", "AwsAsset"},
+		{"Static", "", "AwsAccount"},
+	}
+	for idx, e := range expected {
+		r := rules[idx].(map[string]interface{})
+		assert.Equal(t, e.group, r["group"])
+		assert.Equal(t, e.ruleType, r["type"])
+		assert.Equal(t, e.asset, r["asset"])
+	}
+
+	static := state["static_group"].([]interface{})[0].(map[string]interface{})
+	assert.Empty(t, static["rule"])
+	dynamic := state["dynamic_group"].([]interface{})[0].(map[string]interface{})
+	assert.Empty(t, dynamic["filter"])
+	assert.Empty(t, dynamic["rule"])
+}
+
+func TestStateUpgraderV0SchemaIsFrozen(t *testing.T) {
+	v0Type := resourceCHTPerspectiveV0().CoreConfigSchema().ImpliedType()
+	assert.True(t, v0Type.HasAttribute("group"))
+	for _, attr := range []string{"static_group", "dynamic_group", "other_group_name"} {
+		assert.False(t, v0Type.HasAttribute(attr), attr)
+	}
+	groupType := v0Type.AttributeType("group").ElementType()
+	assert.True(t, groupType.HasAttribute("type"))
+	assert.False(t, groupType.HasAttribute("value_name"))
+}
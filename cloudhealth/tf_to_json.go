@@ -1,6 +1,7 @@
 package cloudhealth
 
 import (
+	"cloudhealth/client"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -9,15 +10,22 @@ import (
 )
 
 func tfToJson(d *schema.ResourceData) (rawData []byte, err error) {
-	var pj PerspectiveJSON
+	pj, err := tfToPerspective(d)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(pj, "", "  ")
+}
+
+func tfToPerspective(d *schema.ResourceData) (pj *client.PerspectiveJSON, err error) {
+	pj = new(client.PerspectiveJSON)
 
-	constants := []*ConstantJSON{
-		NewConstantJSON(StaticGroupType),
-		NewConstantJSON(DynamicGroupType),
-		NewConstantJSON(DynamicGroupBlockType),
+	constants := make([]*client.ConstantJSON, len(constantTypes))
+	for idx, constantType := range constantTypes {
+		constants[idx] = client.NewConstantJSON(constantType)
 	}
 
-	constantsByType := make(map[string]*ConstantJSON)
+	constantsByType := make(map[string]*client.ConstantJSON)
 	for _, constant := range constants {
 		constantsByType[constant.Type] = constant
 	}
@@ -31,72 +39,127 @@ func tfToJson(d *schema.ResourceData) (rawData []byte, err error) {
 	includeInReports := d.Get("include_in_reports")
 	pj.Schema.Include_in_reports = strconv.FormatBool(includeInReports.(bool))
 
-	tfGroups := getArray(d, "group")
+	schemaExtra, constantsExtra := unnestExtra(jsonToExtra(d.Get("extra_json")), "constants")
+	pj.Schema.Extra = schemaExtra
+
+	// The rules of static groups go before those of dynamic groups
+	tfStaticGroups := getArray(d, "static_group")
+	tfDynamicGroups := getArray(d, "dynamic_group")
+	tfGroups := append(append(make([]interface{}, 0), tfStaticGroups...), tfDynamicGroups...)
 	tfConstants := getArray(d, "constant")
+	tfRules := getArray(d, "rule")
+
+	apiOrder := d.Get("rule_order").(string) == apiRuleOrder
+	if !apiOrder && len(tfRules) > 0 {
+		return nil, fmt.Errorf("Top level rule blocks are only allowed with rule_order = \"api\"")
+	}
 
 	if len(tfGroups) > 0 {
-		err = fixRefIDs(tfGroups, tfConstants)
+		err = fixRefIDs(tfGroups, tfConstants, refIDsByKey(d))
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.Set("static_group", tfStaticGroups)
 		if err != nil {
 			return nil, err
 		}
 
-		err = d.Set("group", tfGroups)
+		err = d.Set("dynamic_group", tfDynamicGroups)
 		if err != nil {
 			return nil, err
 		}
 	}
+	// ref_ids of keys being removed aren't reused for new values
+	oldRefIdByKey, _ := d.GetChange("group_ref_ids")
+	err = d.Set("group_ref_ids", groupRefIDs(tfGroups))
+	if err != nil {
+		return nil, err
+	}
 
-	for _, tfGroup := range tfGroups {
+	// Values named by value_name blocks when last applied go back to their
+	// own name if the block is removed
+	oldDynamicGroups, _ := d.GetChange("dynamic_group")
+	oldDynamicGroupList, _ := oldDynamicGroups.([]interface{})
+	previousValueNames := valueNamesByGroup(oldDynamicGroupList)
+	nextRefId := nextRefID(tfGroups, tfConstants, oldRefIdByKey.(map[string]interface{}))
+
+	for groupIdx, tfGroup := range tfGroups {
 		tfGroup := tfGroup.(map[string]interface{})
 		refId := tfGroup["ref_id"].(string)
 		name := tfGroup["name"].(string)
-		groupType := tfGroup["type"].(string)
+		groupType := "filter"
+		if groupIdx >= len(tfStaticGroups) {
+			groupType = "categorize"
+		}
+
+		tfFilters, _ := tfGroup["filter"].([]interface{})
+		tfGroupRules, _ := tfGroup["rule"].([]interface{})
+		if apiOrder && len(tfFilters)+len(tfGroupRules) > 0 {
+			return nil, fmt.Errorf("Group %s: with rule_order = \"api\" all rules must be top level rule blocks", name)
+		}
 
 		var constantType string
-		if tfGroup["type"].(string) == "categorize" {
+		if groupType == "categorize" {
 			// Convert any dynamic groups for this group (if it's a Dynamic Group Block)
-			dynamicGroupConstantItems := dynamicGroupConstantItemsToJson(refId, tfConstants)
-			constantsByType[DynamicGroupType].List = append(constantsByType[DynamicGroupType].List, dynamicGroupConstantItems...)
-			constantType = DynamicGroupBlockType
-		} else if tfGroup["type"].(string) == "filter" {
-			constantType = StaticGroupType
+			tfValueNames, _ := tfGroup["value_name"].([]interface{})
+			dynamicGroupConstantItems := dynamicGroupConstantItemsToJson(refId, tfConstants, tfValueNames, previousValueNames[refId], &nextRefId)
+			constantsByType[client.DynamicGroupType].List = append(constantsByType[client.DynamicGroupType].List, dynamicGroupConstantItems...)
+			constantType = client.DynamicGroupBlockType
+
+			// Filter rules on a Dynamic Group Block go before its categorize rules
+			filters, err := rulesToJson(refId, name, "filter", tfFilters)
+			if err != nil {
+				return nil, err
+			}
+			pj.Schema.Rules = append(pj.Schema.Rules, filters...)
 		} else {
-			return nil, fmt.Errorf("Unknown group type: %s. Expected filter or categorize", tfGroup["type"])
+			constantType = client.StaticGroupType
 		}
 
 		// Convert any rules
-		rules, err := rulesToJson(refId, name, groupType, tfGroup["rule"].([]interface{}))
+		rules, err := rulesToJson(refId, name, groupType, tfGroupRules)
 		if err != nil {
 			return nil, err
 		}
 		pj.Schema.Rules = append(pj.Schema.Rules, rules...)
 
 		// Add a constant for this group
-		constantItem := ConstantItem{
+		constantItem := client.ConstantItem{
 			Name:   name,
 			Ref_id: refId,
+			Extra:  constantExtra(tfConstants, constantType, refId),
 		}
 		constant := constantsByType[constantType]
 		constant.List = append(constant.List, constantItem)
 	}
 
-	err = addOtherConstants(tfConstants, constantsByType)
+	if apiOrder {
+		rules, err := orderedRulesToJson(tfStaticGroups, tfDynamicGroups, tfRules)
+		if err != nil {
+			return nil, err
+		}
+		pj.Schema.Rules = rules
+	}
+
+	err = addOtherConstants(tfConstants, d.Get("other_group_name").(string), constantsByType)
 	if err != nil {
 		return nil, err
 	}
 
 	// Only add constants that have something in them
 	for _, constantGroup := range constants {
+		_, constantGroup.Extra = unnestExtra(constantsExtra, constantGroup.Type)
 		if len(constantGroup.List) > 0 {
 			pj.Schema.Constants = append(pj.Schema.Constants, *constantGroup)
 		}
 	}
-	pj.Schema.Merges = make([]interface{}, 0)
+	pj.Schema.Merges = mergesToJson(getArray(d, "merge"))
 
-	return json.MarshalIndent(pj, "", "  ")
+	return pj, nil
 }
 
-func fixRefIDs(groups []interface{}, constants []interface{}) error {
+func fixRefIDs(groups []interface{}, constants []interface{}, refIdByKey map[string]string) error {
 	/* This is to reconcile the ref_id on groups with the ones in constants.
 
 	   Groups are an ordered list and yet also identified by their ref_id.
@@ -104,7 +167,11 @@ func fixRefIDs(groups []interface{}, constants []interface{}) error {
 	   use a list. When groups are reordered, the computed ref_id fields stay put;
 	   they do not follow the rest of the groups contents.
 
-	   So we use the "constants" structure to reconcile these situations.
+	   A group with a key is identified by it: refIdByKey, from the computed
+	   group_ref_ids, remembers the ref_id of every key. This always wins, so
+	   keyed groups can be renamed and reordered at the same time.
+
+	   Otherwise we use the "constants" structure to reconcile these situations.
 
 	   If the group is renamed in-place, the new name won't have an entry in
 	   constants, so it's presumed to keep its ref_id.
@@ -112,16 +179,20 @@ func fixRefIDs(groups []interface{}, constants []interface{}) error {
 	   If the group is re-ordered, we look up the ref_ids by the name in the
 	   constants structure.
 
-	   If you both re-order and re-name, it will correct the ref_ids of all the
-	   other groups, but the reordered group with the new name will be given a
-	   new ref_id
+	   If you both re-order and re-name a group without a key, it will correct
+	   the ref_ids of all the other groups, but the reordered group with the new
+	   name will be given a new ref_id
 	*/
 
 	refIdByNameFromConstants := make(map[string]string)
 	maxRefId := 0
 	for _, c := range constants {
 		c := c.(map[string]interface{})
-		refIdByNameFromConstants[c["name"].(string)] = c["ref_id"].(string)
+		// Only groups are looked up by name; a value of a dynamic group may
+		// share the name of a group
+		if c["constant_type"] != client.DynamicGroupType {
+			refIdByNameFromConstants[c["name"].(string)] = c["ref_id"].(string)
+		}
 		constantRefIdInt, err := strconv.Atoi(c["ref_id"].(string))
 		if err != nil {
 			return fmt.Errorf("Group with non integer ref_id: %s", c["ref_id"])
@@ -130,68 +201,189 @@ func fixRefIDs(groups []interface{}, constants []interface{}) error {
 			maxRefId = constantRefIdInt + 1
 		}
 	}
+	for _, refId := range refIdByKey {
+		if refIdInt, err := strconv.Atoi(refId); err == nil && refIdInt >= maxRefId {
+			maxRefId = refIdInt + 1
+		}
+	}
 	usedRefIds := make(map[string]bool)
+	claimedByKey := make(map[string]bool)
+	fixed := make(map[int]bool)
+
+	// Groups with a known key get the ref_id recorded for it
+	keySeen := make(map[string]bool)
+	for idx, g := range groups {
+		g := g.(map[string]interface{})
+		key := stringOrNil(g["key"])
+		if key == "" {
+			continue
+		}
+		if keySeen[key] {
+			return fmt.Errorf("Two groups with the same key: %s", key)
+		}
+		keySeen[key] = true
+		if refId, ok := refIdByKey[key]; ok {
+			g["ref_id"] = refId
+			usedRefIds[refId] = true
+			claimedByKey[refId] = true
+			fixed[idx] = true
+		}
+	}
 
 	// Go through and apply the ref_id from the constant to anything that matches the same name in the group
-	for _, g := range groups {
+	for idx, g := range groups {
 		g := g.(map[string]interface{})
 		groupName := g["name"].(string)
+		if fixed[idx] {
+			continue
+		}
 		if constantRefId, ok := refIdByNameFromConstants[groupName]; ok {
-			g["ref_id"] = constantRefId
+			if claimedByKey[constantRefId] {
+				// The group that had this name now has another one
+				continue
+			}
 			if usedRefIds[constantRefId] == true {
 				return fmt.Errorf("Two groups with the same name: %s", groupName)
 			}
+			g["ref_id"] = constantRefId
 			usedRefIds[constantRefId] = true
+			fixed[idx] = true
 		}
 	}
 
-	// Now for any group who name is not in constants, either use its exising
-	// ref_id (we assume this meant a rename) or, if it doesn't have one,
-	// generate a unique one
-	for _, g := range groups {
+	// Now for any other group, either use its exising ref_id (we assume this
+	// meant a rename) or, if it doesn't have one, generate a unique one
+	for idx, g := range groups {
 		g := g.(map[string]interface{})
-		groupName := g["name"].(string)
-		if _, inConstants := refIdByNameFromConstants[groupName]; inConstants {
-			// Already fixed ref_id above
+		if fixed[idx] {
 			continue
 		}
 
 		groupRefId := g["ref_id"].(string)
 		if groupRefId != "" && usedRefIds[groupRefId] == false {
 			// Group was renamed; stick with the existing groupRefId
+			usedRefIds[groupRefId] = true
 			continue
 		}
 
 		// Group is new - assign a new ref id
 		// Must be an integer that is not already in use
 		g["ref_id"] = strconv.Itoa(maxRefId)
+		usedRefIds[g["ref_id"].(string)] = true
 		maxRefId++
 	}
 
 	return nil
 }
 
-func dynamicGroupConstantItemsToJson(groupRefId string, constants []interface{}) []ConstantItem {
-	result := make([]ConstantItem, 0)
+// groupRefIDs maps the key of each group that has one to its ref_id
+func groupRefIDs(groups []interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, g := range groups {
+		g := g.(map[string]interface{})
+		if key := stringOrNil(g["key"]); key != "" {
+			result[key] = g["ref_id"]
+		}
+	}
+	return result
+}
 
+// dynamicGroupConstantItemsToJson lists the Dynamic Group constants of a
+// dynamic group, one per value, named after its value_name blocks. Values with
+// a value_name that CloudHealth hasn't seen yet get a new constant numbered
+// from nextRefId. Values that lost their value_name since the last apply,
+// listed in previousValueNames, are named after the value again.
+func dynamicGroupConstantItemsToJson(groupRefId string, constants []interface{}, tfValueNames []interface{}, previousValueNames []interface{}, nextRefId *int) []client.ConstantItem {
+	result := make([]client.ConstantItem, 0)
+
+	nameByVal := make(map[string]string)
+	for _, v := range tfValueNames {
+		v := v.(map[string]interface{})
+		nameByVal[v["val"].(string)] = v["name"].(string)
+	}
+	previouslyNamed := make(map[string]bool)
+	for _, v := range previousValueNames {
+		previouslyNamed[v.(map[string]interface{})["val"].(string)] = true
+	}
+
+	seen := make(map[string]bool)
 	for _, c := range constants {
 		c := c.(map[string]interface{})
 		if c["blk_id"] != groupRefId {
 			continue
 		}
+		val := c["val"].(string)
+		name := c["name"].(string)
+		if valueName, ok := nameByVal[val]; ok {
+			name = valueName
+		} else if previouslyNamed[val] {
+			name = val
+		}
+		seen[val] = true
 		blk_id := groupRefId
-		result = append(result, ConstantItem{
-			Name:   c["name"].(string),
+		result = append(result, client.ConstantItem{
+			Name:   name,
 			Ref_id: c["ref_id"].(string),
 			Blk_id: &blk_id,
-			Val:    c["val"].(string),
+			Val:    val,
+			Extra:  jsonToExtra(c["extra_json"]),
 		})
 	}
+
+	for _, v := range tfValueNames {
+		v := v.(map[string]interface{})
+		val := v["val"].(string)
+		if seen[val] {
+			continue
+		}
+		seen[val] = true
+		blk_id := groupRefId
+		result = append(result, client.ConstantItem{
+			Name:   v["name"].(string),
+			Ref_id: strconv.Itoa(*nextRefId),
+			Blk_id: &blk_id,
+			Val:    val,
+		})
+		*nextRefId++
+	}
+	return result
+}
+
+// valueNamesByGroup maps the ref_id of each dynamic group to its value_name
+// blocks
+func valueNamesByGroup(groups []interface{}) map[string][]interface{} {
+	result := make(map[string][]interface{})
+	for _, g := range groups {
+		g := g.(map[string]interface{})
+		valueNames, _ := g["value_name"].([]interface{})
+		if refId := stringOrNil(g["ref_id"]); refId != "" && len(valueNames) > 0 {
+			result[refId] = valueNames
+		}
+	}
 	return result
 }
 
-func rulesToJson(groupRefId string, groupName string, groupType string, rules []interface{}) (result []RuleJSON, err error) {
-	result = make([]RuleJSON, len(rules))
+// nextRefID returns a ref_id that no group, constant or group key uses yet
+func nextRefID(groups []interface{}, constants []interface{}, refIdByKey map[string]interface{}) int {
+	next := 0
+	for _, items := range [][]interface{}{groups, constants} {
+		for _, item := range items {
+			refId, err := strconv.Atoi(stringOrNil(item.(map[string]interface{})["ref_id"]))
+			if err == nil && refId >= next {
+				next = refId + 1
+			}
+		}
+	}
+	for _, refId := range refIdByKey {
+		if refId, err := strconv.Atoi(stringOrNil(refId)); err == nil && refId >= next {
+			next = refId + 1
+		}
+	}
+	return next
+}
+
+func rulesToJson(groupRefId string, groupName string, groupType string, rules []interface{}) (result []client.RuleJSON, err error) {
+	result = make([]client.RuleJSON, len(rules))
 
 	for ruleIdx, r := range rules {
 		r := r.(map[string]interface{})
@@ -217,37 +409,93 @@ func rulesToJson(groupRefId string, groupName string, groupType string, rules []
 		} else {
 			rj.Condition = nil
 		}
+
+		var conditionExtra map[string]json.RawMessage
+		rj.Extra, conditionExtra = unnestExtra(jsonToExtra(r["extra_json"]), "condition")
+		if conditionExtra != nil {
+			if rj.Condition == nil {
+				rj.Condition = new(client.ConditionJSON)
+			}
+			rj.Condition.Extra = conditionExtra
+		}
 	}
 	return result, nil
 }
 
-func conditionsToJson(conditions []interface{}, combineWith string) (result *ConditionJSON) {
+// orderedRulesToJson converts the top level rules used with
+// rule_order = "api", keeping their order. Each names the group it sends
+// assets to.
+func orderedRulesToJson(staticGroups []interface{}, dynamicGroups []interface{}, rules []interface{}) ([]client.RuleJSON, error) {
+	groupByName := make(map[string]map[string]interface{})
+	groupTypeByName := make(map[string]string)
+	for _, g := range staticGroups {
+		g := g.(map[string]interface{})
+		groupByName[g["name"].(string)] = g
+		groupTypeByName[g["name"].(string)] = "filter"
+	}
+	for _, g := range dynamicGroups {
+		g := g.(map[string]interface{})
+		groupByName[g["name"].(string)] = g
+		groupTypeByName[g["name"].(string)] = "categorize"
+	}
+
+	result := make([]client.RuleJSON, 0, len(rules))
+	for ruleIdx, r := range rules {
+		r := r.(map[string]interface{})
+		groupName := stringOrNil(r["group"])
+		group, ok := groupByName[groupName]
+		if !ok {
+			return nil, fmt.Errorf("Rule %d: there is no group named %s", ruleIdx, groupName)
+		}
+
+		// A rule has the type of its group, except for filters on categorize groups
+		groupType := groupTypeByName[groupName]
+		ruleType := stringOrNil(r["type"])
+		if ruleType == "" {
+			ruleType = groupType
+		}
+		if ruleType != groupType && !(ruleType == "filter" && groupType == "categorize") {
+			return nil, fmt.Errorf("Rule %d: a %s rule can't be used with %s group %s", ruleIdx, ruleType, groupType, groupName)
+		}
+
+		rule, err := rulesToJson(group["ref_id"].(string), groupName, ruleType, []interface{}{r})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rule...)
+	}
+	return result, nil
+}
+
+func conditionsToJson(conditions []interface{}, combineWith string) (result *client.ConditionJSON) {
 	if len(conditions) == 0 {
 		return nil
 	}
-	result = new(ConditionJSON)
-	result.Clauses = make([]ClauseJSON, len(conditions))
+	result = new(client.ConditionJSON)
+	result.Clauses = make([]client.ClauseJSON, len(conditions))
 	result.Combine_with = combineWith
 	for idx, condition := range conditions {
 		condition := condition.(map[string]interface{})
-		result.Clauses[idx] = ClauseJSON{
+		result.Clauses[idx] = client.ClauseJSON{
 			Field:     convertStringArray(condition["field"]),
 			Tag_field: convertStringArray(condition["tag_field"]),
 			Op:        stringOrNil(condition["op"]),
 			Val:       stringOrNil(condition["val"]),
+			Extra:     jsonToExtra(condition["extra_json"]),
 		}
 	}
 	return result
 }
 
-func constantToJson(tfConstant map[string]interface{}) (constantType string, constantItem ConstantItem) {
+func constantToJson(tfConstant map[string]interface{}) (constantType string, constantItem client.ConstantItem) {
 	constantType = tfConstant["constant_type"].(string)
-	constantItem = ConstantItem{
+	constantItem = client.ConstantItem{
 		Ref_id: stringOrNil(tfConstant["ref_id"]),
 		Name:   stringOrNil(tfConstant["name"]),
 		Val:    stringOrNil(tfConstant["val"]),
+		Extra:  jsonToExtra(tfConstant["extra_json"]),
 	}
-	if constantType == DynamicGroupType {
+	if constantType == client.DynamicGroupType {
 		blk_id := stringOrNil(tfConstant["blk_id"])
 		constantItem.Blk_id = &blk_id
 	}
@@ -257,17 +505,32 @@ func constantToJson(tfConstant map[string]interface{}) (constantType string, con
 	return constantType, constantItem
 }
 
-func addOtherConstants(tfConstants []interface{}, constantsByType map[string]*ConstantJSON) error {
+// constantExtra finds the unknown fields of the constant of a group
+func constantExtra(tfConstants []interface{}, constantType string, refId string) map[string]json.RawMessage {
+	for _, c := range tfConstants {
+		c := c.(map[string]interface{})
+		if c["constant_type"] == constantType && c["ref_id"] == refId {
+			return jsonToExtra(c["extra_json"])
+		}
+	}
+	return nil
+}
+
+func addOtherConstants(tfConstants []interface{}, otherGroupName string, constantsByType map[string]*client.ConstantJSON) error {
 	// Add "other" constants
 	// These are constants that have literally is_other == "true" or dynamic
-	// groups with empty blk_ids
+	// groups with empty blk_ids. The "Other" group keeps its ref_id when
+	// renamed by other_group_name.
 	for _, tfConstant := range tfConstants {
 		tfConstant := tfConstant.(map[string]interface{})
 
 		if tfConstant["is_other"].(string) == "true" ||
-			(tfConstant["constant_type"].(string) == DynamicGroupType && tfConstant["blk_id"] == "") {
+			(tfConstant["constant_type"].(string) == client.DynamicGroupType && tfConstant["blk_id"] == "") {
 
 			constantType, constantItem := constantToJson(tfConstant)
+			if constantItem.Is_other == "true" && otherGroupName != "" {
+				constantItem.Name = otherGroupName
+			}
 
 			constant := constantsByType[constantType]
 			if constant == nil {
@@ -279,6 +542,20 @@ func addOtherConstants(tfConstants []interface{}, constantsByType map[string]*Co
 	return nil
 }
 
+func mergesToJson(tfMerges []interface{}) []client.MergeJSON {
+	result := make([]client.MergeJSON, len(tfMerges))
+	for idx, m := range tfMerges {
+		m := m.(map[string]interface{})
+		result[idx] = client.MergeJSON{
+			Type:  stringOrNil(m["type"]),
+			To:    stringOrNil(m["to"]),
+			From:  convertStringArray(m["from"]),
+			Extra: jsonToExtra(m["extra_json"]),
+		}
+	}
+	return result
+}
+
 func convertStringArray(maybeStringArray interface{}) []string {
 	if maybeStringArray == nil {
 		return nil
@@ -298,6 +575,31 @@ func stringOrNil(s interface{}) string {
 	return s.(string)
 }
 
+// refIDsByKey reads the ref_ids recorded for the keys of the configured
+// groups. When the plan adds a key, the new group_ref_ids is unknown until
+// apply, so the ones recorded in the state are used.
+func refIDsByKey(d *schema.ResourceData) map[string]string {
+	configured := make(map[string]bool)
+	for _, block := range []string{"static_group", "dynamic_group"} {
+		for _, g := range getArray(d, block) {
+			if key := stringOrNil(g.(map[string]interface{})["key"]); key != "" {
+				configured[key] = true
+			}
+		}
+	}
+
+	result := make(map[string]string)
+	old, new := d.GetChange("group_ref_ids")
+	for _, refIdByKey := range []interface{}{old, new} {
+		for key, refId := range refIdByKey.(map[string]interface{}) {
+			if configured[key] {
+				result[key] = refId.(string)
+			}
+		}
+	}
+	return result
+}
+
 func getArray(d *schema.ResourceData, field string) []interface{} {
 	if v, ok := d.GetOk(field); ok {
 		return v.([]interface{})
@@ -1,9 +1,14 @@
 package cloudhealth
 
 import (
+	"cloudhealth/client"
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/stretchr/testify/assert"
 
+	"io/ioutil"
 	"testing"
 )
 
@@ -12,29 +17,27 @@ func TestMissingRefIds(t *testing.T) {
 	rd := resource.Data(&terraform.InstanceState{
 		ID: "1234",
 		Attributes: map[string]string{
-			"name":                                   "My Name",
-			"include_in_reports":                     "true",
-			"group.#":                                "2",
-			"group.0.name":                           "New Group",
-			"group.0.type":                           "filter",
-			"group.0.rule.#":                         "1",
-			"group.0.rule.0.asset":                   "AwsAccount",
-			"group.0.rule.0.condition.#":             "1",
-			"group.0.rule.0.condition.0.field.#":     "1",
-			"group.0.rule.0.condition.0.field.0":     "Account Name",
-			"group.0.rule.0.condition.0.op":          "=",
-			"group.0.rule.0.condition.0.val":         "My Account",
-			"group.1.name":                           "Existing Group",
-			"group.1.type":                           "filter",
-			"group.1.ref_id":                         "1",
-			"group.1.rule.#":                         "1",
-			"group.1.rule.0.asset":                   "AwsAsset",
-			"group.1.rule.0.condition.#":             "1",
-			"group.1.rule.0.condition.0.field.#":     "0",
-			"group.1.rule.0.condition.0.tag_field.#": "1",
-			"group.1.rule.0.condition.0.tag_field.0": "Name",
-			"group.1.rule.0.condition.0.op":          "=",
-			"group.1.rule.0.condition.0.val":         "My Name",
+			"name":                                          "My Name",
+			"include_in_reports":                            "true",
+			"static_group.#":                                "2",
+			"static_group.0.name":                           "New Group",
+			"static_group.0.rule.#":                         "1",
+			"static_group.0.rule.0.asset":                   "AwsAccount",
+			"static_group.0.rule.0.condition.#":             "1",
+			"static_group.0.rule.0.condition.0.field.#":     "1",
+			"static_group.0.rule.0.condition.0.field.0":     "Account Name",
+			"static_group.0.rule.0.condition.0.op":          "=",
+			"static_group.0.rule.0.condition.0.val":         "My Account",
+			"static_group.1.name":                           "Existing Group",
+			"static_group.1.ref_id":                         "1",
+			"static_group.1.rule.#":                         "1",
+			"static_group.1.rule.0.asset":                   "AwsAsset",
+			"static_group.1.rule.0.condition.#":             "1",
+			"static_group.1.rule.0.condition.0.field.#":     "0",
+			"static_group.1.rule.0.condition.0.tag_field.#": "1",
+			"static_group.1.rule.0.condition.0.tag_field.0": "Name",
+			"static_group.1.rule.0.condition.0.op":          "=",
+			"static_group.1.rule.0.condition.0.val":         "My Name",
 		},
 	})
 	b, err := tfToJson(rd)
@@ -43,7 +46,7 @@ func TestMissingRefIds(t *testing.T) {
 	newRD := resource.TestResourceData()
 	jsonToTF(b, newRD)
 
-	refId := rd.Get("group.0.ref_id")
+	refId := rd.Get("static_group.0.ref_id")
 	assert.NotEmpty(t, refId)
 	assert.NotEqual(t, refId, "1")
 }
@@ -53,15 +56,14 @@ func TestDynamicGroupsPreserved(t *testing.T) {
 	rd := resource.Data(&terraform.InstanceState{
 		ID: "1234",
 		Attributes: map[string]string{
-			"name":                 "My Name",
-			"include_in_reports":   "true",
-			"group.#":              "1",
-			"group.0.name":         "New Group",
-			"group.0.ref_id":       "1",
-			"group.0.type":         "categorize",
-			"group.0.rule.#":       "1",
-			"group.0.rule.0.asset": "AwsAccount",
-			"constant.#":           "2",
+			"name":                         "My Name",
+			"include_in_reports":           "true",
+			"dynamic_group.#":              "1",
+			"dynamic_group.0.name":         "New Group",
+			"dynamic_group.0.ref_id":       "1",
+			"dynamic_group.0.rule.#":       "1",
+			"dynamic_group.0.rule.0.asset": "AwsAccount",
+			"constant.#":                   "2",
 
 			"constant.0.constant_type": "Dynamic Group",
 			"constant.0.ref_id":        "2",
@@ -91,3 +93,322 @@ func TestDynamicGroupsPreserved(t *testing.T) {
 	assertEqual(t, newRD, "constant.1.ref_id", "1")
 	assertEqual(t, newRD, "constant.1.name", "New Group")
 }
+
+func TestTopLevelRulesNeedApiRuleOrder(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                  "My Name",
+			"include_in_reports":    "true",
+			"rule_order":            "group",
+			"static_group.#":        "1",
+			"static_group.0.name":   "Static",
+			"static_group.0.ref_id": "1",
+			"rule.#":                "1",
+			"rule.0.group":          "Static",
+			"rule.0.asset":          "AwsAccount",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.NotNil(t, err)
+}
+
+func TestGroupRulesWithApiRuleOrder(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                        "My Name",
+			"include_in_reports":          "true",
+			"rule_order":                  "api",
+			"static_group.#":              "1",
+			"static_group.0.name":         "Static",
+			"static_group.0.ref_id":       "1",
+			"static_group.0.rule.#":       "1",
+			"static_group.0.rule.0.asset": "AwsAccount",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.NotNil(t, err)
+}
+
+func TestApiRuleOrderUnknownGroup(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                  "My Name",
+			"include_in_reports":    "true",
+			"rule_order":            "api",
+			"static_group.#":        "1",
+			"static_group.0.name":   "Static",
+			"static_group.0.ref_id": "1",
+			"rule.#":                "1",
+			"rule.0.group":          "Missing",
+			"rule.0.asset":          "AwsAccount",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.NotNil(t, err)
+}
+
+func TestKeyedGroupsRenamedAndReordered(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                     "My Name",
+			"include_in_reports":       "true",
+			"group_ref_ids.%":          "2",
+			"group_ref_ids.a":          "1",
+			"group_ref_ids.b":          "2",
+			"static_group.#":           "3",
+			"static_group.0.name":      "Bee",
+			"static_group.0.key":       "b",
+			"static_group.0.ref_id":    "1",
+			"static_group.1.name":      "A",
+			"static_group.1.key":       "a",
+			"static_group.1.ref_id":    "2",
+			"static_group.2.name":      "B",
+			"constant.#":               "2",
+			"constant.0.constant_type": "Static Group",
+			"constant.0.name":          "A",
+			"constant.0.ref_id":        "1",
+			"constant.1.constant_type": "Static Group",
+			"constant.1.name":          "B",
+			"constant.1.ref_id":        "2",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.Nil(t, err)
+
+	assertEqual(t, rd, "static_group.0.ref_id", "2")
+	assertEqual(t, rd, "static_group.1.ref_id", "1")
+	// The name B now belongs to a new group
+	assertEqual(t, rd, "static_group.2.ref_id", "3")
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"a": "1", "b": "2"})
+}
+
+func TestKeyedGroupsRenamedWhileAddingKey(t *testing.T) {
+	resource := resourceCHTPerspective()
+	state := &terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                     "My Name",
+			"include_in_reports":       "true",
+			"rule_order":               "group",
+			"group_ref_ids.%":          "2",
+			"group_ref_ids.a":          "1",
+			"group_ref_ids.b":          "2",
+			"static_group.#":           "2",
+			"static_group.0.name":      "A",
+			"static_group.0.key":       "a",
+			"static_group.0.ref_id":    "1",
+			"static_group.1.name":      "B",
+			"static_group.1.key":       "b",
+			"static_group.1.ref_id":    "2",
+			"constant.#":               "2",
+			"constant.0.constant_type": "Static Group",
+			"constant.0.name":          "A",
+			"constant.0.ref_id":        "1",
+			"constant.1.constant_type": "Static Group",
+			"constant.1.name":          "B",
+			"constant.1.ref_id":        "2",
+		},
+	}
+	// The keyed groups swap names, and a new keyed group is added
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":               "My Name",
+		"include_in_reports": true,
+		"static_group": []interface{}{
+			map[string]interface{}{"name": "B", "key": "a"},
+			map[string]interface{}{"name": "A", "key": "b"},
+			map[string]interface{}{"name": "C", "key": "c"},
+		},
+	})
+	diff, err := resource.Diff(context.Background(), state, config, nil)
+	assert.Nil(t, err)
+	assert.True(t, diff.Attributes["group_ref_ids.%"].NewComputed)
+
+	rd, err := schema.InternalMap(resource.Schema).Data(state, diff)
+	assert.Nil(t, err)
+	_, err = tfToJson(rd)
+	assert.Nil(t, err)
+
+	assertEqual(t, rd, "static_group.0.ref_id", "1")
+	assertEqual(t, rd, "static_group.1.ref_id", "2")
+	assertEqual(t, rd, "static_group.2.ref_id", "3")
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"a": "1", "b": "2", "c": "3"})
+}
+
+func TestNewKeyKeepsRefIdOfGroup(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                     "My Name",
+			"include_in_reports":       "true",
+			"static_group.#":           "1",
+			"static_group.0.name":      "A",
+			"static_group.0.key":       "a",
+			"constant.#":               "1",
+			"constant.0.constant_type": "Static Group",
+			"constant.0.name":          "A",
+			"constant.0.ref_id":        "4",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.Nil(t, err)
+
+	assertEqual(t, rd, "static_group.0.ref_id", "4")
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{"a": "4"})
+}
+
+func TestDuplicateGroupKeys(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                "My Name",
+			"include_in_reports":  "true",
+			"static_group.#":      "2",
+			"static_group.0.name": "A",
+			"static_group.0.key":  "same",
+			"static_group.1.name": "B",
+			"static_group.1.key":  "same",
+		},
+	})
+	_, err := tfToJson(rd)
+	assert.NotNil(t, err)
+}
+
+func TestValueNamesNameConstants(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                              "My Name",
+			"include_in_reports":                "true",
+			"dynamic_group.#":                   "1",
+			"dynamic_group.0.name":              "Team",
+			"dynamic_group.0.ref_id":            "1",
+			"dynamic_group.0.value_name.#":      "2",
+			"dynamic_group.0.value_name.0.val":  "team-infra-sre",
+			"dynamic_group.0.value_name.0.name": "Infra SRE",
+			"dynamic_group.0.value_name.1.val":  "team-new",
+			"dynamic_group.0.value_name.1.name": "New Team",
+			"constant.#":                        "3",
+			"constant.0.constant_type":          "Dynamic Group",
+			"constant.0.ref_id":                 "2",
+			"constant.0.blk_id":                 "1",
+			"constant.0.name":                   "team-infra-sre",
+			"constant.0.val":                    "team-infra-sre",
+			"constant.1.constant_type":          "Dynamic Group",
+			"constant.1.ref_id":                 "3",
+			"constant.1.blk_id":                 "1",
+			"constant.1.name":                   "team-web",
+			"constant.1.val":                    "team-web",
+			"constant.2.constant_type":          "Dynamic Group Block",
+			"constant.2.ref_id":                 "1",
+			"constant.2.name":                   "Team",
+		},
+	})
+
+	pj, err := tfToPerspective(rd)
+	assert.Nil(t, err)
+	assert.Equal(t, client.DynamicGroupType, pj.Schema.Constants[0].Type)
+	blk_id := "1"
+	assert.Equal(t, []client.ConstantItem{
+		{Ref_id: "2", Blk_id: &blk_id, Name: "Infra SRE", Val: "team-infra-sre"},
+		{Ref_id: "3", Blk_id: &blk_id, Name: "team-web", Val: "team-web"},
+		// Not seen by CloudHealth yet
+		{Ref_id: "4", Blk_id: &blk_id, Name: "New Team", Val: "team-new"},
+	}, pj.Schema.Constants[0].List)
+}
+
+func TestNewValueNameSkipsRefIdsOfRemovedKeys(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                              "My Name",
+			"include_in_reports":                "true",
+			"group_ref_ids.%":                   "1",
+			"group_ref_ids.gone":                "7",
+			"dynamic_group.#":                   "1",
+			"dynamic_group.0.name":              "Team",
+			"dynamic_group.0.ref_id":            "1",
+			"dynamic_group.0.value_name.#":      "1",
+			"dynamic_group.0.value_name.0.val":  "team-new",
+			"dynamic_group.0.value_name.0.name": "New Team",
+			"constant.#":                        "1",
+			"constant.0.constant_type":          "Dynamic Group Block",
+			"constant.0.ref_id":                 "1",
+			"constant.0.name":                   "Team",
+		},
+	})
+
+	pj, err := tfToPerspective(rd)
+	assert.Nil(t, err)
+	assert.Equal(t, client.DynamicGroupType, pj.Schema.Constants[0].Type)
+	assert.Equal(t, "8", pj.Schema.Constants[0].List[0].Ref_id)
+	assertEqual(t, rd, "group_ref_ids", map[string]interface{}{})
+}
+
+func TestRemovedValueNameRestoresName(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.Data(&terraform.InstanceState{
+		ID: "1234",
+		Attributes: map[string]string{
+			"name":                              "My Name",
+			"include_in_reports":                "true",
+			"dynamic_group.#":                   "1",
+			"dynamic_group.0.name":              "Team",
+			"dynamic_group.0.ref_id":            "1",
+			"dynamic_group.0.value_name.#":      "1",
+			"dynamic_group.0.value_name.0.val":  "team-infra-sre",
+			"dynamic_group.0.value_name.0.name": "Infra SRE",
+			"constant.#":                        "2",
+			"constant.0.constant_type":          "Dynamic Group",
+			"constant.0.ref_id":                 "2",
+			"constant.0.blk_id":                 "1",
+			"constant.0.name":                   "Infra SRE",
+			"constant.0.val":                    "team-infra-sre",
+			"constant.1.constant_type":          "Dynamic Group",
+			"constant.1.ref_id":                 "3",
+			"constant.1.blk_id":                 "1",
+			// Named in the CloudHealth UI
+			"constant.1.name": "Web",
+			"constant.1.val":  "team-web",
+		},
+	})
+	rd.Set("dynamic_group", []interface{}{
+		map[string]interface{}{"name": "Team", "ref_id": "1"},
+	})
+
+	pj, err := tfToPerspective(rd)
+	assert.Nil(t, err)
+	assert.Equal(t, client.DynamicGroupType, pj.Schema.Constants[0].Type)
+	names := make([]string, 0)
+	for _, c := range pj.Schema.Constants[0].List {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"team-infra-sre", "Web"}, names)
+}
+
+func TestOtherGroupNameKeepsRefId(t *testing.T) {
+	resource := resourceCHTPerspective()
+	rd := resource.TestResourceData()
+	bytes, err := ioutil.ReadFile("../test/static_perspective.json")
+	assert.Nil(t, err)
+	assert.Nil(t, jsonToTF(bytes, rd))
+	rd.Set("other_group_name", "Unallocated - needs tagging")
+
+	pj, err := tfToPerspective(rd)
+	assert.Nil(t, err)
+	other := otherGroupConstant(pj)
+	assert.NotNil(t, other)
+	assert.Equal(t, "4", other.Ref_id)
+	assert.Equal(t, "Unallocated - needs tagging", other.Name)
+}
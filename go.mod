@@ -3,6 +3,7 @@ module cloudhealth
 go 1.14
 
 require (
+	github.com/hashicorp/go-cty v1.4.1-0.20200414143053-d3edf31b6320
 	github.com/hashicorp/go-plugin v1.4.1
 	github.com/hashicorp/terraform-plugin-go v0.4.0
 	github.com/hashicorp/terraform-plugin-sdk/v2 v2.9.0
@@ -22,56 +22,94 @@ def main():
         state_attr = state_resource['primary']['attributes']
         print('    name = "%s"' % state_attr['name'])
         print('    include_in_reports = %s' % state_attr['include_in_reports'])
+        if state_attr.get('other_group_name', 'Other') != 'Other': # is default
+            print('    other_group_name = "%s"' % state_attr['other_group_name'])
+        if state_attr.get('rule_order', 'group') != 'group': # is default
+            print('    rule_order = "%s"' % state_attr['rule_order'])
 
-        for group_idx in range(int(state_attr['group.#'])):
-            prefix = 'group.%d.' % group_idx
-            print_group(state_attr, prefix)
+        for block in ('static_group', 'dynamic_group'):
+            for group_idx in range(int(state_attr.get(block + '.#', 0))):
+                prefix = '%s.%d.' % (block, group_idx)
+                print_group(state_attr, prefix, block)
+
+        for rule_idx in range(int(state_attr.get('rule.#', 0))):
+            print_rule(state_attr, 'rule.%d.' % rule_idx, indent='    ')
+
+        for merge_idx in range(int(state_attr.get('merge.#', 0))):
+            print_merge(state_attr, 'merge.%d.' % merge_idx)
 
         print('}')
 
 
-def print_group(state_attr, prefix):
+def print_group(state_attr, prefix, block):
     print()
-    print('    group {')
+    print('    %s {' % block)
+    if state_attr.get(prefix + 'key'):
+        print('        key = "%s"' % state_attr[prefix + 'key'])
     print('        name = "%s"' % state_attr[prefix + 'name'])
-    print('        type = "%s"' % state_attr[prefix + 'type'])
+
+    for filter_idx in range(int(state_attr.get(prefix + 'filter.#', 0))):
+        print_rule(state_attr, prefix + 'filter.%d.' % filter_idx, 'filter')
 
     for rule_idx in range(int(state_attr[prefix + 'rule.#'])):
         print_rule(state_attr, prefix + 'rule.%d.' % rule_idx)
 
+    for value_name_idx in range(int(state_attr.get(prefix + 'value_name.#', 0))):
+        value_name_prefix = prefix + 'value_name.%d.' % value_name_idx
+        print()
+        print('        value_name {')
+        print('            val = "%s"' % state_attr[value_name_prefix + 'val'])
+        print('            name = "%s"' % state_attr[value_name_prefix + 'name'])
+        print('        }')
+
     print('    }')
 
 
-def print_rule(state_attr, prefix):
+def print_rule(state_attr, prefix, block='rule', indent='        '):
     print()
-    print('        rule {')
-    print('            asset = "%s"' % state_attr[prefix + 'asset'])
+    print(indent + '%s {' % block)
+    if prefix + 'group' in state_attr: # top level rule
+        print(indent + '    group = "%s"' % state_attr[prefix + 'group'])
+        if state_attr.get(prefix + 'type'):
+            print(indent + '    type = "%s"' % state_attr[prefix + 'type'])
+    print(indent + '    asset = "%s"' % state_attr[prefix + 'asset'])
 
-    if state_attr[prefix + "combine_with"]:
-        print('            combine_with = "%s"' % state_attr[prefix + 'combine_with'])
+    if state_attr.get(prefix + "combine_with", '') not in ('', 'OR'): # is default
+        print(indent + '    combine_with = "%s"' % state_attr[prefix + 'combine_with'])
 
-    print_str_list('            ', state_attr, prefix, 'field')
-    print_str_list('            ', state_attr, prefix, 'tag_field')
+    if block == 'rule':
+        print_str_list(indent + '    ', state_attr, prefix, 'field')
+        print_str_list(indent + '    ', state_attr, prefix, 'tag_field')
 
     for cond_idx in range(int(state_attr[prefix + 'condition.#'])):
-        print_condition(state_attr, prefix + 'condition.%d.' % cond_idx)
+        print_condition(state_attr, prefix + 'condition.%d.' % cond_idx, indent + '    ')
 
-    print('        }')
+    print(indent + '}')
 
 
-def print_condition(state_attr, prefix):
-    print('            condition {')
-    print_str_list('                ', state_attr, prefix, 'field')
-    print_str_list('                ', state_attr, prefix, 'tag_field')
-    if state_attr[prefix + "op"] != '=': # is default
-        print('                op = "%s"' % state_attr[prefix + "op"])
+def print_condition(state_attr, prefix, indent='            '):
+    print(indent + 'condition {')
+    print_str_list(indent + '    ', state_attr, prefix, 'field')
+    print_str_list(indent + '    ', state_attr, prefix, 'tag_field')
+    if state_attr.get(prefix + "op", '') not in ('', '='): # is default
+        print(indent + '    op = "%s"' % state_attr[prefix + "op"])
     if state_attr[prefix + "val"] != "":
-        print('                val = "%s"' % state_attr[prefix + "val"])
-    print('            }')
+        print(indent + '    val = "%s"' % state_attr[prefix + "val"])
+    print(indent + '}')
+
+
+def print_merge(state_attr, prefix):
+    print()
+    print('    merge {')
+    if state_attr[prefix + 'type'] != 'Dynamic Group': # is default
+        print('        type = "%s"' % state_attr[prefix + 'type'])
+    print('        to = "%s"' % state_attr[prefix + 'to'])
+    print_str_list('        ', state_attr, prefix, 'from')
+    print('    }')
 
 
 def print_str_list(indent, state_attr, prefix, field):
-    count = int(state_attr[prefix + field + '.#'])
+    count = int(state_attr.get(prefix + field + '.#', 0))
     if count == 0:
         return
     vals = '", "'.join((state_attr[prefix + field + '.' + str(x)] for x in range(count)))